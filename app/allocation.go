@@ -0,0 +1,124 @@
+package app
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AssetAllocation is the allocation of the assets and the trades
+// to rebalance it, formatted for the dashboard
+type AssetAllocation struct {
+	Date     string
+	Total    string
+	Classes  []AllocationRow
+	Targeted bool
+	Warnings []string
+
+	Rebalance    []TradeRow // selling, without new money
+	Contribution string     // a month of savings
+	Invest       []TradeRow // the contribution, without selling
+}
+
+type AllocationRow struct {
+	Class       string
+	Commodities string
+	Value       string
+	Share       string
+	Target      string
+	Over        bool // more than 5 points above the target
+	Under       bool
+}
+
+type TradeRow struct {
+	Action string
+	Class  string
+	Amount string
+	Share  string
+}
+
+// Allocation returns the asset allocation at market value, with
+// the trades to rebalance it by selling, and to invest a month of
+// savings without selling
+func Allocation(uid, selectedFile string) (AssetAllocation, error) {
+	if selectedFile == "" {
+		return AssetAllocation{}, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return AssetAllocation{}, err
+	}
+	config, err := fire.ConfigFromJournal(journal)
+	if err != nil {
+		return AssetAllocation{}, err
+	}
+	allocation, err := fire.ComputeAllocation(journal, txs, config.Currency, time.Time{})
+	if err != nil {
+		return AssetAllocation{}, err
+	}
+
+	money := func(d decimal.Decimal) string {
+		return pta.Value{Decimal: d, Commodity: config.Currency}.Str()
+	}
+	percent := func(rate float64) string {
+		return fmt.Sprintf("%.1f%%", rate*100)
+	}
+	trades := func(trades []fire.Trade) []TradeRow {
+		var rows []TradeRow
+		for _, trade := range trades {
+			action := "Buy"
+			if trade.Amount.IsNegative() {
+				action = "Sell"
+			}
+			rows = append(rows, TradeRow{
+				Action: action,
+				Class:  trade.Class,
+				Amount: money(trade.Amount.Abs()),
+				Share:  percent(trade.Share),
+			})
+		}
+		return rows
+	}
+
+	result := AssetAllocation{
+		Date:     allocation.Date.Format("2006/01/02"),
+		Total:    money(allocation.Total),
+		Targeted: allocation.Targeted,
+		Warnings: allocation.Warnings,
+	}
+	for _, class := range allocation.Classes {
+		row := AllocationRow{
+			Class:       class.Class,
+			Commodities: strings.Join(class.Commodities, ", "),
+			Value:       money(class.Value),
+			Share:       percent(class.Share),
+			Target:      "-",
+		}
+		if allocation.Targeted {
+			row.Target = percent(class.Target)
+			row.Over = class.Share-class.Target > 0.05
+			row.Under = class.Target-class.Share > 0.05
+		}
+		result.Classes = append(result.Classes, row)
+	}
+	if !allocation.Targeted {
+		return result, nil
+	}
+
+	result.Rebalance = trades(fire.Rebalance(allocation, decimal.Zero, true))
+	report := fire.Plan(journal, txs, config, time.Now())
+	if savings := report.Savings.Div(decimal.NewFromInt(12)).Round(2); savings.IsPositive() {
+		result.Contribution = money(savings)
+		result.Invest = trades(fire.Rebalance(allocation, savings, false))
+	}
+	return result, nil
+}
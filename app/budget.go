@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/shopspring/decimal"
+)
+
+// BudgetSummary is the budget of the last month of the journal,
+// formatted for the dashboard
+type BudgetSummary struct {
+	Month     string
+	Rollover  bool
+	Lines     []BudgetRow
+	Overspent int
+	Defined   bool // whether the journal has periodic transactions
+}
+
+type BudgetRow struct {
+	Account   string
+	Budgeted  string
+	Actual    string
+	Remaining string
+	Used      string
+	Overspent bool
+}
+
+// Budget returns the budgets against the expenses of the last
+// month with transactions
+func Budget(uid, selectedFile string, rollover bool) (BudgetSummary, error) {
+	if selectedFile == "" {
+		return BudgetSummary{}, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return BudgetSummary{}, err
+	}
+	budgets := journal.AllBudgets()
+	summary := BudgetSummary{Rollover: rollover, Defined: len(budgets) > 0}
+	report := pta.BudgetReport(txs, budgets, pta.Monthly, rollover)
+	if len(report) == 0 {
+		return summary, nil
+	}
+
+	month := report[len(report)-1]
+	summary.Month = month.Start.Format("January 2006")
+	for _, line := range month.Lines {
+		money := func(d decimal.Decimal) string {
+			return pta.Value{Decimal: d, Commodity: line.Commodity}.Str()
+		}
+		summary.Lines = append(summary.Lines, BudgetRow{
+			Account:   line.Account,
+			Budgeted:  money(line.Budgeted),
+			Actual:    money(line.Actual),
+			Remaining: money(line.Remaining),
+			Used:      fmt.Sprintf("%.0f%%", line.Used*100),
+			Overspent: line.Overspent(),
+		})
+		if line.Overspent() {
+			summary.Overspent++
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Envelopes are the envelope balances of the last months,
+// formatted for the dashboard
+type Envelopes struct {
+	Months    []string
+	Available []string // to assign, at the end of each month
+	Rows      []EnvelopeRow
+}
+
+type EnvelopeRow struct {
+	Envelope string
+	Balances []EnvelopeBalance // by month
+}
+
+type EnvelopeBalance struct {
+	Amount    string
+	Overspent bool
+}
+
+// EnvelopeBalances returns the balances of the budget: envelopes
+// over the last 6 months
+func EnvelopeBalances(uid, selectedFile string) (Envelopes, error) {
+	if selectedFile == "" {
+		return Envelopes{}, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	_, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return Envelopes{}, err
+	}
+	report := pta.EnvelopeReport(txs, pta.Monthly)
+	report = report[max(0, len(report)-6):]
+
+	values := func(values []pta.Value) string {
+		if len(values) == 0 {
+			return "-"
+		}
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = v.Str()
+		}
+		return strings.Join(strs, ", ")
+	}
+
+	var envelopes Envelopes
+	type key struct {
+		envelope string
+		code     string
+	}
+	var keys []key
+	for _, month := range report {
+		envelopes.Months = append(envelopes.Months, month.Start.Format("Jan 2006"))
+		envelopes.Available = append(envelopes.Available, values(month.Available))
+		for _, line := range month.Lines {
+			if k := (key{line.Envelope, line.Commodity.Code}); !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return Envelopes{}, nil
+	}
+	slices.SortFunc(keys, func(a, b key) int {
+		if c := strings.Compare(a.envelope, b.envelope); c != 0 {
+			return c
+		}
+		return strings.Compare(a.code, b.code)
+	})
+
+	for _, k := range keys {
+		row := EnvelopeRow{Envelope: k.envelope}
+		for _, month := range report {
+			balance := EnvelopeBalance{Amount: "-"}
+			i := slices.IndexFunc(month.Lines, func(l pta.EnvelopeLine) bool {
+				return l.Envelope == k.envelope && l.Commodity.Code == k.code
+			})
+			if i != -1 {
+				line := month.Lines[i]
+				balance.Amount = pta.Value{Decimal: line.Balance, Commodity: line.Commodity}.Str()
+				balance.Overspent = line.Overspent()
+			} else if len(row.Balances) > 0 && row.Balances[len(row.Balances)-1].Amount != "-" {
+				// an empty envelope without activity
+				balance.Amount = pta.Value{Decimal: decimal.Zero, Commodity: pta.CommodityFromCode(k.code)}.Str()
+			}
+			row.Balances = append(row.Balances, balance)
+		}
+		envelopes.Rows = append(envelopes.Rows, row)
+	}
+	return envelopes, nil
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+)
+
+// ExportTransactions writes the transactions of the journal
+// as json, csv or in the journal format
+func ExportTransactions(uid, selectedFile, format string, w io.Writer) error {
+	if selectedFile == "" {
+		return fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	_, txs, err := journals.load(absFilepath)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return pta.EncodeJSON(w, txs)
+	case "csv":
+		return pta.EncodeCSV(w, txs)
+	case "journal":
+		for _, tx := range txs {
+			_, err = io.WriteString(w, pta.WriteTransaction(tx))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown export format '%s'", format)
+}
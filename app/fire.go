@@ -0,0 +1,150 @@
+package app
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FireSummary is the FI report, formatted for the dashboard
+type FireSummary struct {
+	Months         []FireMonth // the last 12
+	Expenses       string
+	SavingsRate    string
+	FINumber       string
+	WithdrawalRate string
+	Portfolio      string
+	Progress       string
+	YearsToFI      string
+	RealReturn     string
+	Warnings       []string
+
+	// the Monte Carlo simulation, fixed withdrawals
+	Returns     string
+	Success     string
+	Retirement  string
+	Bands       []FireBand // every 5 years
+	SimulateErr error
+}
+
+type FireBand struct {
+	Year          int
+	P10, P50, P90 string
+}
+
+type FireMonth struct {
+	Month       string
+	Income      string
+	Expenses    string
+	SavingsRate string
+}
+
+// FirePlan returns the savings rate and FI projections of the
+// journal, up to today
+func FirePlan(uid, selectedFile string) (FireSummary, error) {
+	if selectedFile == "" {
+		return FireSummary{}, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return FireSummary{}, err
+	}
+	config, err := fire.ConfigFromJournal(journal)
+	if err != nil {
+		return FireSummary{}, err
+	}
+	report := fire.Plan(journal, txs, config, time.Now())
+
+	money := func(d decimal.Decimal) string {
+		return pta.Value{Decimal: d, Commodity: config.Currency}.Str()
+	}
+	percent := func(rate float64) string {
+		return fmt.Sprintf("%.1f%%", rate*100)
+	}
+	summary := FireSummary{
+		Expenses:       money(report.Expenses),
+		SavingsRate:    percent(report.SavingsRate),
+		FINumber:       money(report.FINumber),
+		WithdrawalRate: percent(config.WithdrawalRate),
+		Portfolio:      money(report.Portfolio),
+		Progress:       percent(report.Progress),
+		YearsToFI:      fmt.Sprintf("%.1f", report.YearsToFI),
+		RealReturn:     percent(config.RealReturn),
+		Warnings:       report.Warnings,
+	}
+	if report.YearsToFI < 0 {
+		summary.YearsToFI = "never"
+	}
+	for _, month := range report.Months[max(0, len(report.Months)-12):] {
+		summary.Months = append(summary.Months, FireMonth{
+			Month:       month.Start.Format("2006/01"),
+			Income:      money(month.Income),
+			Expenses:    money(month.Expenses),
+			SavingsRate: percent(month.SavingsRate),
+		})
+	}
+
+	result, sim, err := simulate(uid, journal, report)
+	if err != nil {
+		summary.SimulateErr = err
+		return summary, nil
+	}
+	summary.Returns = "parametric"
+	if len(sim.History) > 0 {
+		summary.Returns = fmt.Sprintf("bootstrapped from %d years", len(sim.History))
+	}
+	summary.Success = percent(result.Success)
+	summary.Retirement = fmt.Sprintf("retiring in %d years for %d years", sim.YearsToRetire, sim.Years)
+	for i, band := range result.Portfolio {
+		if (i+1)%5 != 0 && i != len(result.Portfolio)-1 {
+			continue
+		}
+		summary.Bands = append(summary.Bands, FireBand{
+			Year: band.Year,
+			P10:  money(decimal.NewFromFloat(band.P10).Round(0)),
+			P50:  money(decimal.NewFromFloat(band.P50).Round(0)),
+			P90:  money(decimal.NewFromFloat(band.P90).Round(0)),
+		})
+	}
+	return summary, nil
+}
+
+// the simulation runs on the server, with a fixed seed so the
+// dashboard doesn't change on each reload
+func simulate(uid string, journal pta.Journal, report fire.Report) (fire.SimulationResult, fire.Simulation, error) {
+	model, err := fire.ModelFromJournal(journal)
+	if err != nil {
+		return fire.SimulationResult{}, fire.Simulation{}, err
+	}
+	if value, found := journal.Setting(fire.HistorySetting); found {
+		// the history must be in the user's directory
+		userDir := filepath.Join(root, uid)
+		historyPath := path.Clean(pta.ParsePath(filepath.Dir(journal.Filepath), value))
+		if rel, err := filepath.Rel(userDir, historyPath); err != nil || strings.HasPrefix(rel, "..") {
+			return fire.SimulationResult{}, fire.Simulation{}, fmt.Errorf("%s must be in your directory", fire.HistorySetting)
+		}
+		file, err := os.Open(historyPath)
+		if err != nil {
+			return fire.SimulationResult{}, fire.Simulation{}, err
+		}
+		defer file.Close()
+		model.History, err = fire.LoadHistory(file)
+		if err != nil {
+			return fire.SimulationResult{}, fire.Simulation{}, err
+		}
+	}
+	sim := fire.SimulationFromPlan(report, model)
+	sim.Runs = 2000
+	result, err := fire.Simulate(sim)
+	return result, sim, err
+}
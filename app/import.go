@@ -0,0 +1,110 @@
+package app
+
+import (
+	"fireside/pkg/importer"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type ImportPreview struct {
+	Plaintext  string // the new transactions
+	Duplicates []ImportDuplicate
+	Version    string // of the journal the duplicates were found in
+}
+
+// ImportDuplicate is an imported transaction likely
+// already in the journal
+type ImportDuplicate struct {
+	Plaintext string
+	Existing  string
+	Reason    string
+}
+
+// ImportStatement converts an uploaded statement (csv, ofx or qif)
+// into journal text, to be reviewed by the user before it gets
+// appended with AppendPlaintext. The transactions that are likely
+// already in the selected journal are set apart, so the user can
+// skip them. Entries that failed to import are reported in the
+// error, alongside the preview of the others
+func ImportStatement(uid, selectedFile, format string, statement, rules io.Reader) (ImportPreview, error) {
+	if selectedFile == "" {
+		return ImportPreview{}, fmt.Errorf("no journal file selected")
+	}
+	r, err := importer.ParseRules(rules)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+
+	var txs []pta.Transaction
+	var importErr error
+	switch format {
+	case "csv":
+		txs, importErr = importer.ImportCSV(statement, r)
+	case "ofx":
+		txs, importErr = importer.ImportOFX(statement, r)
+	case "qif":
+		txs, importErr = importer.ImportQIF(statement, r)
+	default:
+		return ImportPreview{}, fmt.Errorf("unknown statement format '%s'", format)
+	}
+
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, existing, err := journals.load(absFilepath)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+	categorizer, err := journals.categorizer(absFilepath)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+	fresh, duplicates := importer.SplitDuplicates(existing, txs)
+	// the explicit rules of the import come first
+	categorizer = categorizer.WithRules(r)
+	suggested := categorizer.Categorize(fresh)
+
+	preview := ImportPreview{Version: journal.Version}
+	for _, dup := range duplicates {
+		// categorized too, in case the user keeps it
+		tx := []pta.Transaction{txs[dup.Index]}
+		categorizer.Categorize(tx)
+		preview.Duplicates = append(preview.Duplicates, ImportDuplicate{
+			Plaintext: pta.WriteTransaction(tx[0]),
+			Existing:  pta.WriteTransaction(dup.Existing),
+			Reason:    dup.Reason,
+		})
+	}
+
+	sb := strings.Builder{}
+	for i, tx := range fresh {
+		if suggestion, found := suggested[i]; found {
+			// comments are dropped when appended
+			fmt.Fprintf(&sb, "; suggested %s\r\n", suggestion)
+		}
+		sb.WriteString(pta.WriteTransaction(tx))
+	}
+	preview.Plaintext = sb.String()
+	return preview, importErr
+}
+
+// SuggestCategory returns the most likely expense (or income)
+// account for the description, learned from the selected journal
+func SuggestCategory(uid, selectedFile, desc string) (importer.Suggestion, bool, error) {
+	if selectedFile == "" {
+		return importer.Suggestion{}, false, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	categorizer, err := journals.categorizer(absFilepath)
+	if err != nil {
+		return importer.Suggestion{}, false, err
+	}
+	suggestion, found := categorizer.Suggest(desc)
+	return suggestion, found, nil
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fireside/pkg/importer"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Parsing the journal on every request gets slow as the journal
+// grows, so the parsed journals are kept in memory, keyed by their
+// absolute path. An entry stays valid as long as the journal and
+// all of its included files are unchanged: the size and mtime are
+// checked on every lookup, and the file is only hashed when those
+// changed (so touching a file doesn't force a re-parse)
+
+type fileStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+	missing bool // valid until the file is created
+}
+
+type cachedJournal struct {
+	journal     pta.Journal
+	txs         []pta.Transaction
+	err         error
+	stamps      []fileStamp
+	categorizer *importer.Categorizer // learned from txs when needed
+	sync.Mutex
+}
+
+type JournalCache struct {
+	entries map[string]*cachedJournal
+	sync.Mutex
+}
+
+var journals = JournalCache{
+	entries: make(map[string]*cachedJournal),
+}
+
+// returns the parsed journal, re-parsing it only if the journal
+// or one of its includes changed since the last call. The returned
+// transactions are shared with the cache and must not be modified
+func (c *JournalCache) load(absFilepath string) (pta.Journal, []pta.Transaction, error) {
+	entry := c.entry(absFilepath)
+	entry.Lock()
+	defer entry.Unlock()
+
+	if entry.stamps != nil && entry.valid() {
+		return entry.journal, entry.txs, entry.err
+	}
+
+	journal, txs, err := pta.ParseJournal(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		// failed to open, nothing worth caching
+		entry.stamps = nil
+		return journal, txs, err
+	}
+	entry.journal = journal
+	entry.txs = txs
+	entry.err = err
+	entry.categorizer = nil
+	entry.stamps = stampJournal(journal)
+	return journal, txs, err
+}
+
+// updates the cached entry in place after transactions were
+// appended to the journal file, so the next load doesn't need
+// to re-parse it. The entry must be of the previous version of
+// the file, and the file of the appended one, otherwise it was
+// changed by someone else and the entry is dropped. It is dropped
+// too when the file ends with a year or an apply account, the
+// transactions were not parsed with them
+func (c *JournalCache) appended(absFilepath, previous string, journal pta.Journal, txs []pta.Transaction) {
+	entry := c.entry(absFilepath)
+	entry.Lock()
+	defer entry.Unlock()
+
+	if entry.stamps == nil {
+		return
+	}
+	stamp, err := stampFile(absFilepath)
+	if err != nil || entry.journal.Version != previous || fmt.Sprintf("%x", stamp.hash) != journal.Version ||
+		entry.journal.OpenDirectives() {
+		entry.stamps = nil
+		return
+	}
+	// copy, readers may still hold the previous slice
+	updated := make([]pta.Transaction, 0, len(entry.txs)+len(txs))
+	updated = append(updated, entry.txs...)
+	entry.txs = append(updated, txs...)
+	entry.journal.Version = journal.Version
+	entry.stamps[0] = stamp
+	entry.categorizer = nil
+}
+
+// the categorizer learned from the journal, rebuilt after
+// the journal changed
+func (c *JournalCache) categorizer(absFilepath string) (*importer.Categorizer, error) {
+	_, _, err := c.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return nil, err
+	}
+	entry := c.entry(absFilepath)
+	entry.Lock()
+	defer entry.Unlock()
+	if entry.categorizer == nil {
+		entry.categorizer = importer.NewCategorizer(entry.txs, importer.Rules{})
+	}
+	return entry.categorizer, nil
+}
+
+func (c *JournalCache) entry(absFilepath string) *cachedJournal {
+	c.Lock()
+	defer c.Unlock()
+	entry, found := c.entries[absFilepath]
+	if !found {
+		entry = &cachedJournal{}
+		c.entries[absFilepath] = entry
+	}
+	return entry
+}
+
+func (e *cachedJournal) valid() bool {
+	for i, stamp := range e.stamps {
+		info, err := os.Stat(stamp.path)
+		if stamp.missing && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil || stamp.missing {
+			return false
+		}
+		if info.Size() == stamp.size && info.ModTime().Equal(stamp.modTime) {
+			continue
+		}
+		latest, err := stampFile(stamp.path)
+		if err != nil || latest.hash != stamp.hash {
+			return false
+		}
+		e.stamps[i] = latest
+	}
+	return true
+}
+
+// stamps the journal file first, followed by all of its includes
+func stampJournal(journal pta.Journal) (stamps []fileStamp) {
+	var walk func(j pta.Journal)
+	walk = func(j pta.Journal) {
+		stamp, err := stampFile(j.Filepath)
+		if err != nil {
+			// can't be checked, won't be considered valid, unless
+			// it doesn't exist yet (a missing include)
+			stamp.path = j.Filepath
+			stamp.missing = errors.Is(err, fs.ErrNotExist)
+		}
+		stamps = append(stamps, stamp)
+		for _, inc := range j.Includes {
+			walk(inc)
+		}
+	}
+	walk(journal)
+	return stamps
+}
+
+func stampFile(path string) (stamp fileStamp, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stamp, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return stamp, err
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return stamp, err
+	}
+	stamp.path = path
+	stamp.modTime = info.ModTime()
+	stamp.size = info.Size()
+	copy(stamp.hash[:], h.Sum(nil))
+	return stamp, nil
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const cacheTestTx = "2024/01/02 groceries\n" +
+	"    expenses:food  $10.00\n" +
+	"    assets:cash\n\n"
+
+func newTestCache() *JournalCache {
+	return &JournalCache{entries: make(map[string]*cachedJournal)}
+}
+
+func TestJournalCacheMissingInclude(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.journal")
+	inc := filepath.Join(dir, "inc.journal")
+	if err := os.WriteFile(main, []byte("include inc.journal\n\n"+cacheTestTx), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newTestCache()
+	_, txs, err := cache.load(main)
+	if err == nil {
+		t.Errorf("expected an error for the missing include")
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(txs))
+	}
+	// still missing, the entry is valid
+	if entry := cache.entry(main); !entry.valid() {
+		t.Errorf("expected the entry to stay valid while the include is missing")
+	}
+
+	if err := os.WriteFile(inc, []byte(cacheTestTx), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, txs, err = cache.load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 {
+		t.Errorf("expected the transaction of the created include, got %d transactions", len(txs))
+	}
+}
+
+func TestJournalCacheAppended(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.journal")
+	if err := os.WriteFile(main, []byte(cacheTestTx), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newTestCache()
+	appendTx := func(external bool) {
+		journal, _, err := cache.load(main)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs, err := journal.ParseTransactionStrings(cacheTestTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := journal.Version
+		if err := journal.AppendTxs(txs); err != nil {
+			t.Fatal(err)
+		}
+		// edited by someone else before the cache is updated
+		if external {
+			f, err := os.OpenFile(main, os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(cacheTestTx)
+			f.Close()
+		}
+		cache.appended(main, previous, journal, txs)
+	}
+
+	appendTx(false)
+	if entry := cache.entry(main); entry.stamps == nil || len(entry.txs) != 2 {
+		t.Fatalf("expected the entry updated in place, got %d transactions", len(entry.txs))
+	}
+	_, txs, err := cache.load(main)
+	if err != nil || len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d (%v)", len(txs), err)
+	}
+
+	appendTx(true)
+	if entry := cache.entry(main); entry.stamps != nil {
+		t.Errorf("expected the entry dropped after the external edit")
+	}
+	_, txs, err = cache.load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 4 {
+		t.Errorf("expected the externally appended transaction, got %d transactions", len(txs))
+	}
+}
+
+func TestJournalCacheAppendedApplyAccount(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.journal")
+	if err := os.WriteFile(main, []byte(cacheTestTx+"apply account personal\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newTestCache()
+	journal, _, err := cache.load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !journal.OpenDirectives() {
+		t.Fatalf("expected the apply account in effect at the end of the file")
+	}
+	txs, err := journal.ParseTransactionStrings(cacheTestTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := journal.Version
+	if err := journal.AppendTxs(txs); err != nil {
+		t.Fatal(err)
+	}
+	cache.appended(main, previous, journal, txs)
+	if entry := cache.entry(main); entry.stamps != nil {
+		t.Errorf("expected the entry dropped, the appended transaction is under the apply account")
+	}
+
+	_, txs, err = cache.load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[1].Postings[0].Account != "personal:expenses:food" {
+		t.Errorf("expected the appended transaction parsed like the file, got %+v", txs)
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// the size of the chart, in svg units
+const (
+	chartWidth  = 600
+	chartHeight = 240
+	chartLeft   = 90 // room for the amounts
+	chartBottom = 20 // room for the dates
+)
+
+// NetWorthChart is drawn server side, the lines are the points
+// of svg polylines
+type NetWorthChart struct {
+	Width, Height int
+	Left, Bottom  int
+	NetWorth      string
+	Assets        string
+	Liabilities   string
+	Ticks         []ChartTick // amounts
+	Dates         []ChartTick
+	Currency      pta.Commodity
+	Missing       string // commodities without a price
+
+	// the last month
+	Date                                         string
+	TotalNetWorth, TotalAssets, TotalLiabilities string
+}
+
+type ChartTick struct {
+	X, Y  int
+	Label string
+}
+
+// NetWorth returns the monthly net worth of the journal, in the
+// journal currency at the prices of each month
+func NetWorth(uid, selectedFile string) (NetWorthChart, error) {
+	if selectedFile == "" {
+		return NetWorthChart{}, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return NetWorthChart{}, err
+	}
+	// the unit values of the transactions are prices too, as in the returns
+	prices := append(journal.AllPrices(), pta.TransactionPrices(txs)...)
+	// a yearly file starts from its opening balances
+	start, rest := pta.OpeningBalance(txs)
+	series := pta.NetWorthSeries(start, rest, prices, pta.Monthly, journal.DefaultCurrency)
+	return netWorthChart(series, journal.DefaultCurrency), nil
+}
+
+func netWorthChart(series []pta.NetWorthPoint, currency pta.Commodity) NetWorthChart {
+	chart := NetWorthChart{
+		Width:    chartWidth,
+		Height:   chartHeight,
+		Left:     chartLeft,
+		Bottom:   chartHeight - chartBottom,
+		Currency: currency,
+	}
+	if len(series) == 0 {
+		return chart
+	}
+	latest := series[len(series)-1]
+	chart.Date = latest.Date.Format("2006/01/02")
+	chart.TotalNetWorth = pta.Value{Decimal: latest.NetWorth, Commodity: currency}.Str()
+	chart.TotalAssets = pta.Value{Decimal: latest.Assets, Commodity: currency}.Str()
+	chart.TotalLiabilities = pta.Value{Decimal: latest.Liabilities, Commodity: currency}.Str()
+
+	var missing []string
+	for _, point := range series {
+		for _, code := range point.Missing {
+			if !slices.Contains(missing, code) {
+				missing = append(missing, code)
+			}
+		}
+	}
+	chart.Missing = strings.Join(missing, ", ")
+
+	// the range of the amounts, always with zero
+	low, high := decimal.Zero, decimal.Zero
+	for _, point := range series {
+		for _, v := range []decimal.Decimal{point.NetWorth, point.Assets, point.Liabilities} {
+			low, high = decimal.Min(low, v), decimal.Max(high, v)
+		}
+	}
+	if low.Equal(high) {
+		high = low.Add(decimal.NewFromInt(1))
+	}
+
+	x := func(i int) int {
+		if len(series) == 1 {
+			return chartLeft
+		}
+		return chartLeft + i*(chartWidth-chartLeft-10)/(len(series)-1)
+	}
+	y := func(v decimal.Decimal) int {
+		top, bottom := decimal.NewFromInt(10), decimal.NewFromInt(chartHeight-chartBottom)
+		ratio := v.Sub(low).Div(high.Sub(low))
+		return int(bottom.Sub(ratio.Mul(bottom.Sub(top))).IntPart())
+	}
+	polyline := func(value func(pta.NetWorthPoint) decimal.Decimal) string {
+		points := make([]string, len(series))
+		for i, point := range series {
+			points[i] = fmt.Sprintf("%d,%d", x(i), y(value(point)))
+		}
+		return strings.Join(points, " ")
+	}
+	chart.NetWorth = polyline(func(p pta.NetWorthPoint) decimal.Decimal { return p.NetWorth })
+	chart.Assets = polyline(func(p pta.NetWorthPoint) decimal.Decimal { return p.Assets })
+	chart.Liabilities = polyline(func(p pta.NetWorthPoint) decimal.Decimal { return p.Liabilities })
+
+	for i := 0; i <= 4; i++ {
+		v := low.Add(high.Sub(low).Mul(decimal.NewFromInt(int64(i))).Div(decimal.NewFromInt(4))).Round(0)
+		label := pta.Value{Decimal: v, Commodity: currency}.Str()
+		chart.Ticks = append(chart.Ticks, ChartTick{X: chartLeft - 5, Y: y(v), Label: label})
+	}
+	// a date about every 6 labels
+	step := max(1, len(series)/6)
+	for i := 0; i < len(series); i += step {
+		chart.Dates = append(chart.Dates, ChartTick{X: x(i), Y: chartHeight - 5, Label: series[i].Date.Format("2006/01")})
+	}
+	return chart
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// JournalNotes returns the comments kept outside of the
+// transactions, with the files relative to the user's directory
+func JournalNotes(uid, selectedFile string) ([]pta.Note, error) {
+	if selectedFile == "" {
+		return nil, fmt.Errorf("no journal file selected")
+	}
+	userDir := filepath.Join(root, uid)
+	absFilepath := path.Clean(
+		filepath.Join(userDir, selectedFile),
+	)
+	journal, _, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return nil, err
+	}
+	notes := journal.AllNotes()
+	for i := range notes {
+		if rel, err := filepath.Rel(userDir, notes[i].File); err == nil {
+			notes[i].File = rel
+		}
+	}
+	return notes, nil
+}
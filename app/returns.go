@@ -0,0 +1,119 @@
+package app
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountReturn is the performance of an investment account,
+// formatted for the dashboard
+type AccountReturn struct {
+	Account  string
+	Total    PerformanceRow
+	Years    []PerformanceRow
+	Warnings []string
+	Error    error
+}
+
+type PerformanceRow struct {
+	Period        string
+	StartValue    string
+	Contributions string
+	Withdrawals   string
+	Income        string
+	EndValue      string
+	XIRR          string
+	TWR           string
+}
+
+// InvestmentReturns returns the yearly returns of each investment
+// account. The accounts are the ones matching fire:investments, up
+// to the end of the matched component: '^assets:brokerage' groups
+// assets:brokerage:cash and assets:brokerage:vti together
+func InvestmentReturns(uid, selectedFile string) ([]AccountReturn, error) {
+	if selectedFile == "" {
+		return nil, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return nil, err
+	}
+	pattern, err := regexp.Compile(fire.InvestmentsPattern(journal))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fire.InvestmentsSetting, err)
+	}
+
+	var accounts []string
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			match := pattern.FindStringIndex(post.Account)
+			if match == nil {
+				continue
+			}
+			account := post.Account
+			if end := strings.IndexByte(account[match[1]:], ':'); end != -1 {
+				account = account[:match[1]+end]
+			}
+			if !slices.Contains(accounts, account) {
+				accounts = append(accounts, account)
+			}
+		}
+	}
+
+	prices := journal.AllPrices()
+	target := journal.DefaultCurrency
+	returns := make([]AccountReturn, 0, len(accounts))
+	for _, account := range accounts {
+		ret := AccountReturn{Account: account}
+		report, err := pta.InvestmentReturn(txs, prices, "^"+regexp.QuoteMeta(account)+"(:|$)", pta.Yearly, target)
+		if err != nil {
+			ret.Error = err
+			returns = append(returns, ret)
+			continue
+		}
+		for _, perf := range report.Periods {
+			ret.Years = append(ret.Years, performanceRow(perf, target, perf.Start.Format("2006")))
+		}
+		ret.Total = performanceRow(report.Total, target, "all")
+		ret.Warnings = report.Warnings
+		returns = append(returns, ret)
+	}
+	return returns, nil
+}
+
+func performanceRow(perf pta.Performance, target pta.Commodity, period string) PerformanceRow {
+	money := func(d decimal.Decimal) string {
+		return pta.Value{Decimal: d, Commodity: target}.Str()
+	}
+	percent := func(rate float64) string {
+		if math.IsNaN(rate) {
+			return "-"
+		}
+		if math.Abs(rate) < 0.00005 {
+			rate = 0 // not -0.00%
+		}
+		return fmt.Sprintf("%.2f%%", rate*100)
+	}
+	return PerformanceRow{
+		Period:        period,
+		StartValue:    money(perf.StartValue),
+		Contributions: money(perf.Contributions),
+		Withdrawals:   money(perf.Withdrawals),
+		Income:        money(perf.Income),
+		EndValue:      money(perf.EndValue),
+		XIRR:          percent(perf.XIRR),
+		TWR:           percent(perf.TWR),
+	}
+}
@@ -6,25 +6,56 @@ import (
 	"path"
 	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 )
 
-func AppendPlaintext(uid, selectedFile, txStr string) error {
+// AppendPlaintext appends the transactions to the selected journal.
+// The version is the one of the journal the transactions were entered
+// against (see JournalVersion), if the file changed since nothing is
+// written and pta.ErrConflict is returned. Without a version, they
+// are appended to the journal as it is
+func AppendPlaintext(uid, selectedFile, version, txStr string) error {
 	if selectedFile == "" {
 		return fmt.Errorf("no journal file selected")
 	}
 	absFilepath := path.Clean(
 		filepath.Join(root, uid, selectedFile),
 	)
-	journal, _, err := pta.ParseJournal(absFilepath)
+	journal, _, err := journals.load(absFilepath)
 	if err != nil {
 		return err
 	}
+	if version != "" {
+		journal.Version = version
+	}
 	txs, err := journal.ParseTransactionStrings(txStr)
 	if err != nil {
 		return err
 	}
-	return journal.AppendTxs(txs)
+	previous := journal.Version
+	err = journal.AppendTxs(txs)
+	if err != nil {
+		return err
+	}
+	journals.appended(absFilepath, previous, journal, txs)
+	return nil
+}
+
+// JournalVersion returns the version of the selected journal, the
+// hash of its content
+func JournalVersion(uid, selectedFile string) (string, error) {
+	if selectedFile == "" {
+		return "", fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	journal, _, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return "", err
+	}
+	return journal.Version, nil
 }
 
 func RecentTransactions(uid, selectedFile string, since time.Time) ([]pta.Transaction, error) {
@@ -34,7 +65,7 @@ func RecentTransactions(uid, selectedFile string, since time.Time) ([]pta.Transa
 	absFilepath := path.Clean(
 		filepath.Join(root, uid, selectedFile),
 	)
-	_, txs, err := pta.ParseJournal(absFilepath)
+	_, txs, err := journals.load(absFilepath)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +74,67 @@ func RecentTransactions(uid, selectedFile string, since time.Time) ([]pta.Transa
 	return filtered, nil
 }
 
+// FailedAssertions returns the balance assertions of the selected
+// journal that don't hold, they are only reported
+func FailedAssertions(uid, selectedFile string) ([]string, error) {
+	if selectedFile == "" {
+		return nil, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	_, txs, err := journals.load(absFilepath)
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	if errs, ok := pta.CheckBalanceAssertions(txs).(*pta.ParseErrors); ok {
+		for _, err := range errs.Unwrap() {
+			failed = append(failed, err.Error())
+		}
+	}
+	return failed, nil
+}
+
+// JournalAccounts returns the accounts used in the journal, most
+// used first, split into the expense categories and the others
+// (income accounts are neither)
+func JournalAccounts(uid, selectedFile string) (categories, accounts []string, err error) {
+	if selectedFile == "" {
+		return nil, nil, fmt.Errorf("no journal file selected")
+	}
+	absFilepath := path.Clean(
+		filepath.Join(root, uid, selectedFile),
+	)
+	_, txs, err := journals.load(absFilepath)
+	if _, ok := err.(*pta.ParseErrors); err != nil && !ok {
+		return nil, nil, err
+	}
+	uses := make(map[string]int)
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			uses[post.Account]++
+		}
+	}
+	for account := range uses {
+		switch {
+		case strings.HasPrefix(account, "expenses"):
+			categories = append(categories, account)
+		case !strings.HasPrefix(account, "income"):
+			accounts = append(accounts, account)
+		}
+	}
+	byUses := func(a, b string) int {
+		if uses[a] != uses[b] {
+			return uses[b] - uses[a]
+		}
+		return strings.Compare(a, b)
+	}
+	slices.SortFunc(categories, byUses)
+	slices.SortFunc(accounts, byUses)
+	return categories, accounts, nil
+}
+
 func filterTxSince(txs []pta.Transaction, filterDate time.Time) []pta.Transaction {
 	var filtered []pta.Transaction
 	for _, tx := range txs {
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"errors"
+	"fireside/pkg/pta"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendPlaintextVersion(t *testing.T) {
+	root = t.TempDir()
+	journal := filepath.Join(root, "uid", "main.journal")
+	if err := os.MkdirAll(filepath.Dir(journal), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(journal, []byte(cacheTestTx), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the form is rendered with this version
+	version, err := JournalVersion("uid", "main.journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another tab adds an expense
+	if err := AppendPlaintext("uid", "main.journal", version, cacheTestTx); err != nil {
+		t.Fatal(err)
+	}
+	err = AppendPlaintext("uid", "main.journal", version, cacheTestTx)
+	if !errors.Is(err, pta.ErrConflict) {
+		t.Errorf("expected a conflict with the version of the form, got %v", err)
+	}
+
+	txs, err := RecentTransactions("uid", "main.journal", pta.NotDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 {
+		t.Errorf("expected 2 transactions, got %d", len(txs))
+	}
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Journals can be edited outside of fireside (text editor, git pull),
+// so the user files are watched for changes. Affected journals are
+// re-parsed to refresh the cache, and the user's open dashboards are
+// notified so they can reload
+
+// editors often write a file in several steps, wait for
+// the changes to settle before reacting to them
+const settleDelay = 250 * time.Millisecond
+
+// how often the files are checked when the platform
+// does not support change notifications
+const pollInterval = 2 * time.Second
+
+type changeSubscribers struct {
+	kv map[string][]chan struct{}
+	sync.Mutex
+}
+
+var subscribers = changeSubscribers{
+	kv: make(map[string][]chan struct{}),
+}
+
+// SubscribeJournalChanges returns a channel that receives a value
+// whenever one of the user's journals (or included files) changes.
+// Call the returned function to unsubscribe
+func SubscribeJournalChanges(uid string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	subscribers.kv[uid] = append(subscribers.kv[uid], ch)
+
+	unsubscribe := func() {
+		subscribers.Lock()
+		defer subscribers.Unlock()
+		subscribers.kv[uid] = slices.DeleteFunc(subscribers.kv[uid], func(c chan struct{}) bool {
+			return c == ch
+		})
+		if len(subscribers.kv[uid]) == 0 {
+			delete(subscribers.kv, uid)
+		}
+	}
+	return ch, unsubscribe
+}
+
+func notifySubscribers(uid string) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	for _, ch := range subscribers.kv[uid] {
+		// a pending notification already covers this one
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchUserFiles starts watching the user files root directory,
+// must be called after InitUserFilesRootDir
+func WatchUserFiles() {
+	changes := make(chan string, 64)
+	go settleChanges(changes)
+
+	err := watchTree(root, changes)
+	if err != nil {
+		log.Printf("WatchUserFiles: falling back to polling (%s)\r\n", err)
+		go pollTree(root, changes)
+	}
+}
+
+func settleChanges(changes <-chan string) {
+	pending := make(map[string]bool)
+	timer := time.NewTimer(settleDelay)
+	timer.Stop()
+	for {
+		select {
+		case path := <-changes:
+			pending[path] = true
+			timer.Reset(settleDelay)
+		case <-timer.C:
+			onFilesChanged(pending)
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+func onFilesChanged(paths map[string]bool) {
+	users := make(map[string]bool)
+	for path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		uid, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		users[uid] = true
+	}
+	journals.refresh(paths)
+	for uid := range users {
+		notifySubscribers(uid)
+	}
+}
+
+// re-parses the cached journals affected by the changed files
+func (c *JournalCache) refresh(paths map[string]bool) {
+	c.Lock()
+	affected := make([]string, 0)
+	for absFilepath, entry := range c.entries {
+		entry.Lock()
+		for _, stamp := range entry.stamps {
+			if paths[stamp.path] {
+				affected = append(affected, absFilepath)
+				break
+			}
+		}
+		entry.Unlock()
+	}
+	c.Unlock()
+
+	for _, absFilepath := range affected {
+		c.load(absFilepath)
+	}
+}
+
+// fallback for platforms without change notifications, compares
+// the size and mtime of all files at every interval
+func pollTree(dir string, changes chan<- string) {
+	type stat struct {
+		size    int64
+		modTime time.Time
+	}
+	scan := func() map[string]stat {
+		stats := make(map[string]stat)
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			stats[path] = stat{info.Size(), info.ModTime()}
+			return nil
+		})
+		return stats
+	}
+
+	previous := scan()
+	for range time.Tick(pollInterval) {
+		current := scan()
+		for path, st := range current {
+			if prev, found := previous[path]; !found || prev.size != st.size || !prev.modTime.Equal(st.modTime) {
+				changes <- path
+			}
+		}
+		for path := range previous {
+			if _, found := current[path]; !found {
+				changes <- path
+			}
+		}
+		previous = current
+	}
+}
@@ -0,0 +1,94 @@
+//go:build linux
+
+package app
+
+import (
+	"bytes"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const watchMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// watches the directory tree with inotify, new sub-directories
+// are added to the watch list as they are created
+func watchTree(dir string, changes chan<- string) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	dirs := make(map[int]string)
+
+	addTree := func(dir string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			wd, err := unix.InotifyAddWatch(fd, path, watchMask)
+			if err != nil {
+				return err
+			}
+			dirs[wd] = path
+			return nil
+		})
+	}
+
+	err = addTree(dir)
+	if err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := unix.Read(fd, buf)
+			if err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				log.Printf("watchTree: %s\r\n", err)
+				return
+			}
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + unix.SizeofInotifyEvent
+				name := bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00")
+				offset = nameStart + int(event.Len)
+
+				mu.Lock()
+				parent, found := dirs[int(event.Wd)]
+				if event.Mask&unix.IN_IGNORED != 0 {
+					delete(dirs, int(event.Wd))
+				}
+				mu.Unlock()
+				if !found || len(name) == 0 {
+					continue
+				}
+
+				path := filepath.Join(parent, string(name))
+				if event.Mask&unix.IN_ISDIR != 0 {
+					if event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+						if err := addTree(path); err != nil {
+							log.Printf("watchTree: %s\r\n", err)
+						}
+					}
+					continue
+				}
+				changes <- path
+			}
+		}
+	}()
+	return nil
+}
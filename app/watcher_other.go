@@ -0,0 +1,9 @@
+//go:build !linux
+
+package app
+
+import "fmt"
+
+func watchTree(dir string, changes chan<- string) error {
+	return fmt.Errorf("file change notifications not supported")
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchUserFiles(t *testing.T) {
+	root = t.TempDir()
+	journal := filepath.Join(root, "uid", "main.journal")
+	if err := os.MkdirAll(filepath.Dir(journal), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(journal, []byte(cacheTestTx), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := journals.load(journal); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, unsubscribe := SubscribeJournalChanges("uid")
+	defer unsubscribe()
+	other, unsubscribeOther := SubscribeJournalChanges("other")
+	defer unsubscribeOther()
+
+	changes := make(chan string, 64)
+	go settleChanges(changes)
+	if err := watchTree(root, changes); err != nil {
+		go pollTree(root, changes)
+	}
+
+	// edited outside of fireside
+	f, err := os.OpenFile(journal, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(cacheTestTx)
+	f.Close()
+
+	select {
+	case <-changed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected a notification of the change")
+	}
+	select {
+	case <-other:
+		t.Errorf("expected no notification for another user")
+	default:
+	}
+
+	// re-parsed before the subscribers were notified
+	entry := journals.entry(journal)
+	entry.Lock()
+	defer entry.Unlock()
+	if len(entry.txs) != 2 {
+		t.Errorf("expected the cache refreshed with 2 transactions, got %d", len(entry.txs))
+	}
+}
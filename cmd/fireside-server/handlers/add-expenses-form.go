@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fireside/app"
+	"fireside/pkg/pta"
 	"fmt"
+	"html"
 	"log"
 	"net/url"
 	"strings"
@@ -12,13 +15,42 @@ import (
 )
 
 func RenderAddExpenses(c *fiber.Ctx) error {
-	data := fiber.Map{
-		"ReloadRecentTx": c.Locals("ReloadRecentTx"),
+	data := fiber.Map{}
+	// the suggested accounts are the ones used in the journal
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err == nil {
+		data["Categories"], data["Accounts"], err = app.JournalAccounts(sess.ID, sess.SelectedFile)
+		if err != nil {
+			log.Println("RenderAddExpenses:", err)
+		}
+		// the expenses are appended to this version of the journal
+		data["Version"], _ = app.JournalVersion(sess.ID, sess.SelectedFile)
 	}
 	return c.Render("add-expenses.html", data)
 }
 
+// suggests the category of an expense from its description,
+// used to fill the category input when it's empty. Only the
+// expense accounts are suggested, the form files the others
+// under expenses
+func GetSuggestCategory(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	suggestion, found, err := app.SuggestCategory(sess.ID, sess.SelectedFile, c.Query("desc"))
+	if err != nil || !found || !suggestion.Confident() ||
+		!strings.HasPrefix(suggestion.Account, "expense") {
+		return c.JSON(fiber.Map{})
+	}
+	return c.JSON(fiber.Map{
+		"account":    suggestion.Account,
+		"confidence": suggestion.Confidence,
+	})
+}
+
 type addExpensesData struct {
+	Version     string // of the journal the form was rendered with
 	FromAccount string
 	Date        []string
 	ExpAccount  []string
@@ -37,17 +69,40 @@ func PostAddExpenses(c *fiber.Ctx) error {
 	// inputs, reusing names
 	data, err := parseMultiExpenseForm(c.Body())
 	if err != nil {
-		return err
+		return renderAddExpensesError(c, err)
 	}
 	txStr := expenseInputsToPlaintext(data)
-	err = app.AppendPlaintext(sess.ID, sess.SelectedFile, txStr)
+	err = app.AppendPlaintext(sess.ID, sess.SelectedFile, data.Version, txStr)
 	if err != nil {
-		return err
+		return renderAddExpensesError(c, err)
 	}
-	c.Locals("ReloadRecentTx", true)
+	c.Set("HX-Trigger", reloadRecentTxEvent)
 	return RenderAddExpenses(c)
 }
 
+// only the error message is swapped in, so the user
+// doesn't lose what was typed in the form. On a conflict the
+// version of the form is updated, so the expenses can be
+// submitted again once the user checked the journal
+func renderAddExpensesError(c *fiber.Ctx, err error) error {
+	msg := "Error: " + html.EscapeString(err.Error())
+	if errors.Is(err, pta.ErrConflict) {
+		msg = "Error: the journal was modified (in another tab or editor) while you were " +
+			"entering expenses, nothing was saved: check the recent transactions " +
+			"and submit again"
+		if sess, err := parseSessionCookie(c.Cookies("session")); err == nil {
+			version, _ := app.JournalVersion(sess.ID, sess.SelectedFile)
+			msg += fmt.Sprintf(`<input id="add-expenses-version" type="hidden" name="version" value="%s" hx-swap-oob="true">`,
+				html.EscapeString(version))
+		}
+		c.Set("HX-Trigger", reloadRecentTxEvent)
+	}
+	c.Set("HX-Retarget", "#add-expenses-error")
+	c.Set("HX-Reswap", "innerHTML")
+	c.Set("Content-Type", "text/html")
+	return c.SendString(msg)
+}
+
 func parseMultiExpenseForm(body []byte) (data addExpensesData, err error) {
 	for _, input := range bytes.Split(body, []byte{'&'}) {
 		kvpair := bytes.Split(input, []byte{'='})
@@ -79,6 +134,9 @@ func parseMultiExpenseForm(body []byte) (data addExpensesData, err error) {
 
 		case "fromacct":
 			data.FromAccount = val
+
+		case "version":
+			data.Version = val
 		}
 	}
 	return data, nil
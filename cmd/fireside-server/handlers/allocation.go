@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fireside/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type allocationRenderData struct {
+	Allocation app.AssetAllocation
+	Error      error
+}
+
+func RenderAllocation(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	allocation, err := app.Allocation(sess.ID, sess.SelectedFile)
+	return c.Render("allocation.html", allocationRenderData{Allocation: allocation, Error: err})
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fireside/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type budgetRenderData struct {
+	Budget app.BudgetSummary
+	Error  error
+}
+
+func RenderBudget(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	budget, err := app.Budget(sess.ID, sess.SelectedFile, c.QueryBool("rollover"))
+	return c.Render("budget.html", budgetRenderData{Budget: budget, Error: err})
+}
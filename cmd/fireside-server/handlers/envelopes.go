@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fireside/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type envelopesRenderData struct {
+	Envelopes app.Envelopes
+	Error     error
+}
+
+func RenderEnvelopes(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	envelopes, err := app.EnvelopeBalances(sess.ID, sess.SelectedFile)
+	return c.Render("envelopes.html", envelopesRenderData{Envelopes: envelopes, Error: err})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bufio"
+	"fireside/app"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// keeps the connection alive through proxies, and
+// detects when the client went away
+const eventsKeepAlive = 30 * time.Second
+
+// the existing htmx trigger reloading recent-tx, and with it every
+// dashboard panel showing the journal. Sent as a response header when
+// the selected journal changed (new file, added transactions) and over
+// SSE when it changed on disk
+const reloadRecentTxEvent = "ReloadRecentTx"
+
+// StreamEvents pushes Server-Sent Events to the dashboard when the
+// user's journals change on disk. The dashboard turns them into the
+// ReloadRecentTx htmx trigger
+func StreamEvents(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+
+	changes, unsubscribe := app.SubscribeJournalChanges(sess.ID)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		ticker := time.NewTicker(eventsKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-changes:
+				fmt.Fprintf(w, "event: %s\ndata: journal changed\n\n", reloadRecentTxEvent)
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"bytes"
+	"fireside/app"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var exportTypes = map[string]string{
+	"json":    fiber.MIMEApplicationJSONCharsetUTF8,
+	"csv":     "text/csv; charset=utf-8",
+	"journal": fiber.MIMETextPlainCharsetUTF8,
+}
+
+// downloads the transactions of the selected journal,
+// '/api/export?format=json'
+func GetExport(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	format := c.Query("format", "json")
+	contentType, found := exportTypes[format]
+	if !found {
+		return c.Status(fiber.StatusBadRequest).SendString("unknown export format: " + format)
+	}
+
+	// written to a buffer first, so errors are still reported
+	// with a status code
+	var buf bytes.Buffer
+	err = app.ExportTransactions(sess.ID, sess.SelectedFile, format, &buf)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	name := strings.TrimSuffix(filepath.Base(sess.SelectedFile), filepath.Ext(sess.SelectedFile))
+	c.Attachment(name + "." + format)
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(buf.Bytes())
+}
@@ -16,12 +16,11 @@ type pathCrumbs struct {
 }
 
 type fileSelectorRenderData struct {
-	Path           string
-	SelectedFile   string
-	PathCrumbs     []pathCrumbs
-	DirEnts        []fs.DirEntry
-	ReloadRecentTx bool
-	Error          error
+	Path         string
+	SelectedFile string
+	PathCrumbs   []pathCrumbs
+	DirEnts      []fs.DirEntry
+	Error        error
 }
 
 func RenderFileSelector(c *fiber.Ctx) error {
@@ -55,10 +54,9 @@ func RenderFileSelectorWithSession(c *fiber.Ctx, sess sessCookieData) error {
 	}
 	dirPath := path.Clean(c.Params("*"))
 	data := fileSelectorRenderData{
-		Path:           dirPath,
-		PathCrumbs:     makeCrumbs(dirPath),
-		SelectedFile:   sess.SelectedFile,
-		ReloadRecentTx: c.Locals("ReloadRecentTx") == true,
+		Path:         dirPath,
+		PathCrumbs:   makeCrumbs(dirPath),
+		SelectedFile: sess.SelectedFile,
 	}
 	dirents, err := app.DirectoryListing(sess.ID, dirPath)
 	if err != nil {
@@ -140,6 +138,6 @@ func FileSelectorSelect(c *fiber.Ctx) error {
 		}
 		return c.Render("file-selector.html", data)
 	}
-	c.Locals("ReloadRecentTx", true)
+	c.Set("HX-Trigger", reloadRecentTxEvent)
 	return RenderFileSelectorWithSession(c, sess)
 }
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fireside/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type fireRenderData struct {
+	Summary app.FireSummary
+	Error   error
+}
+
+func RenderFire(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	summary, err := app.FirePlan(sess.ID, sess.SelectedFile)
+	return c.Render("fire.html", fireRenderData{Summary: summary, Error: err})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fireside/app"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type importRenderData struct {
+	Preview    string
+	Duplicates []app.ImportDuplicate
+	Version    string // of the journal, checked when appending
+	Imported   bool
+	Error      error
+}
+
+func RenderImport(c *fiber.Ctx) error {
+	return c.Render("import.html", importRenderData{})
+}
+
+// converts the uploaded statement, and renders the resulting
+// transactions so they can be reviewed before being appended
+func PostImportPreview(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	statement, err := openFormFile(c, "statement")
+	if err != nil {
+		return c.Render("import.html", importRenderData{Error: err})
+	}
+	defer statement.Close()
+
+	// the rules are optional for some formats, the
+	// importers report what's missing
+	var rules io.Reader = strings.NewReader("")
+	if file, err := openFormFile(c, "rules"); err == nil {
+		defer file.Close()
+		rules = file
+	}
+
+	preview, err := app.ImportStatement(sess.ID, sess.SelectedFile, c.FormValue("format"), statement, rules)
+	return c.Render("import.html", importRenderData{
+		Preview:    preview.Plaintext,
+		Duplicates: preview.Duplicates,
+		Version:    preview.Version,
+		Error:      err,
+	})
+}
+
+func openFormFile(c *fiber.Ctx, key string) (multipart.File, error) {
+	header, err := c.FormFile(key)
+	if err != nil {
+		return nil, fmt.Errorf("missing %s file", key)
+	}
+	return header.Open()
+}
+
+// appends the reviewed (and possibly edited) transactions, and
+// the likely duplicates the user chose to keep
+func PostImportAppend(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	txStr := c.FormValue("transactions")
+	for _, dup := range c.Request().PostArgs().PeekMulti("duplicates") {
+		txStr += "\n\n" + string(dup)
+	}
+	if strings.TrimSpace(txStr) == "" {
+		return c.Render("import.html", importRenderData{
+			Error: fmt.Errorf("no transactions to add"),
+		})
+	}
+	err = app.AppendPlaintext(sess.ID, sess.SelectedFile, c.FormValue("version"), txStr)
+	if errors.Is(err, pta.ErrConflict) {
+		// the duplicates may have changed too
+		return c.Render("import.html", importRenderData{
+			Error: fmt.Errorf("the journal was modified while you were reviewing the " +
+				"transactions, nothing was added: preview the statement again"),
+		})
+	} else if err != nil {
+		return c.Render("import.html", importRenderData{
+			Preview: txStr,
+			Version: c.FormValue("version"),
+			Error:   err,
+		})
+	}
+	c.Set("HX-Trigger", reloadRecentTxEvent)
+	return c.Render("import.html", importRenderData{Imported: true})
+}
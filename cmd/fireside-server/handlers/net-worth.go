@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fireside/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type netWorthRenderData struct {
+	Chart app.NetWorthChart
+	Error error
+}
+
+func RenderNetWorth(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	chart, err := app.NetWorth(sess.ID, sess.SelectedFile)
+	return c.Render("net-worth.html", netWorthRenderData{Chart: chart, Error: err})
+}
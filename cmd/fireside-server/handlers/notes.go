@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"fireside/app"
+	"fireside/pkg/pta"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type notesRenderData struct {
+	Notes []pta.Note
+	Error error
+}
+
+func RenderNotes(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	notes, err := app.JournalNotes(sess.ID, sess.SelectedFile)
+	return c.Render("notes.html", notesRenderData{Notes: notes, Error: err})
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fireside/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type returnsRenderData struct {
+	Accounts []app.AccountReturn
+	Error    error
+}
+
+func RenderReturns(c *fiber.Ctx) error {
+	sess, err := parseSessionCookie(c.Cookies("session"))
+	if err != nil {
+		c.ClearCookie("session")
+		c.Set("HX-Redirect", "/login")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	accounts, err := app.InvestmentReturns(sess.ID, sess.SelectedFile)
+	return c.Render("returns.html", returnsRenderData{Accounts: accounts, Error: err})
+}
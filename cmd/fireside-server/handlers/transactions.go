@@ -9,6 +9,7 @@ import (
 
 type recentTxRenderData struct {
 	Transactions []string
+	Warnings     []string // failed balance assertions
 }
 
 func RenderRecentTransactions(c *fiber.Ctx) error {
@@ -27,5 +28,6 @@ func RenderRecentTransactions(c *fiber.Ctx) error {
 	data := recentTxRenderData{
 		Transactions: app.TxStringify(txs),
 	}
+	data.Warnings, _ = app.FailedAssertions(sess.ID, sess.SelectedFile)
 	return c.Render("recent-tx.html", data)
 }
@@ -30,6 +30,7 @@ func main() {
 
 	userDataPath := filepath.Join(*wdir, "user_data")
 	app.InitUserFilesRootDir(userDataPath)
+	app.WatchUserFiles()
 
 	tmplEngine := html.New("./www/templates", "")
 	if *devel {
@@ -47,6 +48,10 @@ func main() {
 	app.Use(recover.New())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestSpeed,
+		// compression buffers the event stream
+		Next: func(c *fiber.Ctx) bool {
+			return c.Path() == "/events"
+		},
 	}))
 	app.Use(handlers.ResetLoginExpirationMiddleware())
 
@@ -60,6 +65,14 @@ func main() {
 	tmpl.Get("file-selector/*", handlers.RenderFileSelector)
 	tmpl.Get("add-expenses", handlers.RenderAddExpenses)
 	tmpl.Get("recent-tx", handlers.RenderRecentTransactions)
+	tmpl.Get("import", handlers.RenderImport)
+	tmpl.Get("notes", handlers.RenderNotes)
+	tmpl.Get("net-worth", handlers.RenderNetWorth)
+	tmpl.Get("fire", handlers.RenderFire)
+	tmpl.Get("returns", handlers.RenderReturns)
+	tmpl.Get("allocation", handlers.RenderAllocation)
+	tmpl.Get("budget", handlers.RenderBudget)
+	tmpl.Get("envelopes", handlers.RenderEnvelopes)
 
 	api := app.Group("/api/")
 	api.Post("user/create", handlers.UserCreate)
@@ -68,6 +81,12 @@ func main() {
 	api.Post("file-selector/new/*", handlers.FileSelectorNew)
 	api.Post("file-selector/select/*", handlers.FileSelectorSelect)
 	api.Post("add-expenses", handlers.PostAddExpenses)
+	api.Get("suggest-category", handlers.GetSuggestCategory)
+	api.Post("import/preview", handlers.PostImportPreview)
+	api.Post("import/append", handlers.PostImportAppend)
+	api.Get("export", handlers.GetExport)
+
+	app.Get("/events", handlers.StreamEvents)
 
 	app.Static("/assets/", "./www/assets/")
 	app.Static("/", "./www/pages/")
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// prints the asset allocation at market value against the target
+// of the journal, and the trades to rebalance it
+func runAllocation(args []string) error {
+	flags := flag.NewFlagSet("allocation", flag.ExitOnError)
+	exchange := flags.String("exchange", "", "Commodity of the report, the journal currency by default")
+	end := flags.String("end", "", "Date of the allocation (YYYY/MM/DD or YYYY-MM-DD), the last price by default")
+	contribution := flags.String("contribution", "0", "New money to invest while rebalancing")
+	noSell := flags.Bool("no-sell", false, "Rebalance with the contribution only, without selling")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	var date time.Time
+	if *end != "" {
+		var err error
+		date, err = parseEndDate(*end)
+		if err != nil {
+			return err
+		}
+	}
+	amount, err := decimal.NewFromString(strings.ReplaceAll(*contribution, ",", ""))
+	if err != nil {
+		return fmt.Errorf("bad contribution '%s'", *contribution)
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	currency := journal.DefaultCurrency
+	if *exchange != "" {
+		currency = pta.CommodityFromCode(*exchange)
+	}
+	allocation, err := fire.ComputeAllocation(journal, txs, currency, date)
+	if err != nil {
+		return err
+	}
+
+	money := func(d decimal.Decimal) string {
+		return pta.Value{Decimal: d, Commodity: currency}.Str()
+	}
+	fmt.Printf("allocation on %s\n\n", allocation.Date.Format("2006/01/02"))
+	fmt.Printf("%-16s  %14s  %7s  %7s  %s\n", "class", "value", "share", "target", "commodities")
+	for _, class := range allocation.Classes {
+		fmt.Printf("%-16s  %14s  %6.1f%%  %6.1f%%  %s\n", class.Class, money(class.Value),
+			class.Share*100, class.Target*100, strings.Join(class.Commodities, ", "))
+	}
+	fmt.Printf("%-16s  %14s\n", "total", money(allocation.Total))
+
+	if !allocation.Targeted {
+		fmt.Printf("\nno target, set %s to rebalance\n", fire.TargetSetting)
+	} else if trades := fire.Rebalance(allocation, amount, !*noSell); len(trades) == 0 {
+		fmt.Printf("\nnothing to rebalance\n")
+	} else {
+		fmt.Printf("\nto rebalance with %s:\n", money(amount))
+		for _, trade := range trades {
+			action := "buy"
+			if trade.Amount.IsNegative() {
+				action = "sell"
+			}
+			fmt.Printf("%-4s  %-16s  %14s  (%.1f%% after)\n", action, trade.Class, money(trade.Amount.Abs()), trade.Share*100)
+		}
+	}
+
+	for _, warning := range allocation.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// prints the budget of the periodic transactions against the
+// expenses of each period
+func runBudget(args []string) error {
+	flags := flag.NewFlagSet("budget", flag.ExitOnError)
+	period := flags.String("period", "monthly", "Period: monthly, quarterly or yearly")
+	rollover := flags.Bool("rollover", false, "Carry what is left of the budgets to the next period")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	p, err := pta.ParsePeriod(*period)
+	if err != nil {
+		return err
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	budgets := journal.AllBudgets()
+	if len(budgets) == 0 {
+		return fmt.Errorf("no budget, add periodic transactions: '~ monthly'")
+	}
+
+	for i, budget := range pta.BudgetReport(txs, budgets, p, *rollover) {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s - %s\n", budget.Start.Format("2006/01/02"), budget.End.Format("2006/01/02"))
+		fmt.Printf("%-32s  %14s  %14s  %14s  %7s\n", "account", "budgeted", "actual", "remaining", "used")
+		for _, line := range budget.Lines {
+			money := func(d decimal.Decimal) string {
+				return pta.Value{Decimal: d, Commodity: line.Commodity}.Str()
+			}
+			over := ""
+			if line.Overspent() {
+				over = "  overspent"
+			}
+			fmt.Printf("%-32s  %14s  %14s  %14s  %6.1f%%%s\n", line.Account,
+				money(line.Budgeted), money(line.Actual), money(line.Remaining), line.Used*100, over)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// prints the cash flow statement of each period, by section and
+// counterpart account
+func runCashFlow(args []string) error {
+	flags := flag.NewFlagSet("cashflow", flag.ExitOnError)
+	period := flags.String("period", "yearly", "Period: monthly, quarterly or yearly")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	p, err := pta.ParsePeriod(*period)
+	if err != nil {
+		return err
+	}
+	_, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	lots := func(lots []pta.Lot) string {
+		if len(lots) == 0 {
+			return "0"
+		}
+		strs := make([]string, len(lots))
+		for i, lot := range lots {
+			strs[i] = pta.Value{Decimal: lot.Amount, Commodity: lot.Commodity}.Str()
+		}
+		return strings.Join(strs, ", ")
+	}
+	for i, statement := range pta.ComputeCashFlowStatement(txs, p) {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s - %s\n", statement.Start.Format("2006/01/02"), statement.End.Format("2006/01/02"))
+		fmt.Printf("  %-34s  %s\n", "opening cash", lots(statement.Opening))
+		for _, section := range []struct {
+			name     string
+			accounts map[string][]pta.Lot
+		}{
+			{"operating", statement.Operating},
+			{"investing", statement.Investing},
+			{"financing", statement.Financing},
+			{"equity", statement.Equity},
+		} {
+			if len(section.accounts) == 0 {
+				continue
+			}
+			fmt.Printf("  %s\n", section.name)
+			for _, account := range slices.Sorted(maps.Keys(section.accounts)) {
+				fmt.Printf("    %-32s  %s\n", account, lots(section.accounts[account]))
+			}
+		}
+		fmt.Printf("  %-34s  %s\n", "net cash flow", lots(statement.NetCashFlow()))
+		fmt.Printf("  %-34s  %s\n", "closing cash", lots(statement.Closing))
+		if err := statement.Check(); err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+)
+
+// checks the journal: the parse errors, then the balance
+// assertions (which the other commands don't check)
+func runCheck(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	_, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return pta.CheckBalanceAssertions(txs)
+}
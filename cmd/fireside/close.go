@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// closes the books at the end date: prints the closing transaction
+// of the income and expenses, and the opening transaction of the
+// next file. Or writes them, to split a journal per year
+func runClose(args []string) error {
+	flags := flag.NewFlagSet("close", flag.ExitOnError)
+	end := flags.String("end", "", "Last date of the books (YYYY/MM/DD or YYYY-MM-DD)")
+	appendClosing := flags.Bool("append", false, "Append the closing transaction to the journal")
+	newFile := flags.String("new", "", "New journal to start with the opening transaction")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	if *end == "" {
+		return fmt.Errorf("missing the end date, -end YYYY/MM/DD")
+	}
+	endDate, err := parseEndDate(*end)
+	if err != nil {
+		return err
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if tx.Date.After(endDate) {
+			fmt.Fprintf(os.Stderr, "warning: transactions after %s are left out, the first on %s\n",
+				endDate.Format("2006/01/02"), tx.Date.Format("2006/01/02"))
+			break
+		}
+	}
+
+	closing, hasClosing := pta.ClosingTransaction(txs, endDate)
+	opening, hasOpening := pta.OpeningTransaction(txs, endDate)
+	if !*appendClosing && *newFile == "" {
+		if hasClosing {
+			fmt.Print(pta.WriteTransaction(closing))
+		}
+		if hasOpening {
+			fmt.Print(pta.WriteTransaction(opening))
+		}
+		return nil
+	}
+
+	if *appendClosing {
+		if !hasClosing {
+			fmt.Fprintln(os.Stderr, "nothing to close, the income and expenses are zero")
+		} else if err := journal.AppendTxs([]pta.Transaction{closing}); err != nil {
+			return err
+		} else {
+			fmt.Fprintf(os.Stderr, "appended the closing transaction to %s\n", flags.Arg(0))
+		}
+	}
+	if *newFile != "" {
+		f, err := os.OpenFile(*newFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if hasOpening {
+			if _, err := f.WriteString(pta.WriteTransaction(opening)); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "started %s with the opening balances\n", *newFile)
+	}
+	return nil
+}
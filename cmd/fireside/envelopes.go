@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// prints the envelopes of each period: what was assigned, spent
+// and is left, and the income still available to assign
+func runEnvelopes(args []string) error {
+	flags := flag.NewFlagSet("envelopes", flag.ExitOnError)
+	period := flags.String("period", "monthly", "Period: monthly, quarterly or yearly")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	p, err := pta.ParsePeriod(*period)
+	if err != nil {
+		return err
+	}
+	_, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	values := func(values []pta.Value) string {
+		if len(values) == 0 {
+			return "0"
+		}
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = v.Str()
+		}
+		return strings.Join(strs, ", ")
+	}
+	for i, envelopes := range pta.EnvelopeReport(txs, p) {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s - %s\n", envelopes.Start.Format("2006/01/02"), envelopes.End.Format("2006/01/02"))
+		fmt.Printf("%-32s  %14s  %14s  %14s\n", "envelope", "assigned", "spent", "balance")
+		for _, line := range envelopes.Lines {
+			money := func(d decimal.Decimal) string {
+				return pta.Value{Decimal: d, Commodity: line.Commodity}.Str()
+			}
+			over := ""
+			if line.Overspent() {
+				over = "  overspent"
+			}
+			fmt.Printf("%-32s  %14s  %14s  %14s%s\n", line.Envelope,
+				money(line.Assigned), money(line.Spent), money(line.Balance), over)
+		}
+		fmt.Printf("income %s, unbudgeted %s, available to assign %s\n",
+			values(envelopes.Income), values(envelopes.Unbudgeted), values(envelopes.Available))
+	}
+	return nil
+}
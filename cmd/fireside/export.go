@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fireside/pkg/beancount"
+	"fireside/pkg/pta"
+	"fmt"
+	"os"
+)
+
+// prints the journal in another format
+func runExport(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a format (beancount) and a journal")
+	}
+	format, path := args[0], args[1]
+	if format != "beancount" {
+		return fmt.Errorf("unknown export format '%s'", format)
+	}
+
+	journal, txs, err := pta.ParseJournal(path)
+	if err != nil {
+		return err
+	}
+	return beancount.Export(os.Stdout, journal, txs)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// prints the savings rate, the FI number and the years left to
+// reach it, the settings come from the journal
+func runFire(args []string) error {
+	flags := flag.NewFlagSet("fire", flag.ExitOnError)
+	end := flags.String("end", "", "Last date of the report (YYYY/MM/DD or YYYY-MM-DD)")
+	months := flags.Int("months", 12, "Number of months of savings rates to print")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	var endDate time.Time
+	if *end != "" {
+		var err error
+		endDate, err = parseEndDate(*end)
+		if err != nil {
+			return err
+		}
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	config, err := fire.ConfigFromJournal(journal)
+	if err != nil {
+		return err
+	}
+	report := fire.Plan(journal, txs, config, endDate)
+
+	money := func(d decimal.Decimal) string {
+		return pta.Value{Decimal: d, Commodity: config.Currency}.Str()
+	}
+
+	for _, month := range report.Months[max(0, len(report.Months)-*months):] {
+		fmt.Printf("%s  income %14s  expenses %14s  saved %5.1f%%\n", month.Start.Format("2006/01"),
+			money(month.Income), money(month.Expenses),
+			month.SavingsRate*100)
+	}
+	fmt.Println()
+	fmt.Printf("yearly expenses  %s\n", money(report.Expenses))
+	fmt.Printf("savings rate     %.1f%%\n", report.SavingsRate*100)
+	fmt.Printf("FI number        %s (at %.2f%% withdrawal)\n", money(report.FINumber), config.WithdrawalRate*100)
+	fmt.Printf("net worth        %s (%.1f%% of FI)\n", money(report.Portfolio), report.Progress*100)
+	if report.YearsToFI < 0 {
+		fmt.Printf("years to FI      never, at %.1f%% real return and the current savings\n", config.RealReturn*100)
+	} else {
+		fmt.Printf("years to FI      %.1f (at %.1f%% real return)\n", report.YearsToFI, config.RealReturn*100)
+	}
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fireside/pkg/beancount"
+	"fireside/pkg/importer"
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prints the imported transactions, or appends them to a journal
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing import format (csv, ofx, qif, beancount, json)")
+	}
+	format := args[0]
+
+	flags := flag.NewFlagSet("import "+format, flag.ExitOnError)
+	rulesPath := flags.String("rules", "", "Rules file (fields, accounts and categories)")
+	journalPath := flags.String("append", "", "Journal to append the transactions to (default: print them)")
+	learnPath := flags.String("learn", "", "Journal to learn the categories from (default: the -append journal)")
+	duplicates := flags.String("duplicates", "ask", "Transactions likely already in the journal: ask, skip or keep")
+	flags.Parse(args[1:])
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one statement file")
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if *rulesPath == "" && format != "qif" && format != "beancount" && format != "json" {
+		// the rules are optional for qif, to rename the accounts and categories
+		return fmt.Errorf("%s import requires -rules", format)
+	}
+	rules, err := importer.ParseRules(strings.NewReader(""))
+	if *rulesPath != "" {
+		rules, err = importer.LoadRules(*rulesPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	var txs []pta.Transaction
+	var prices []pta.Price
+	switch format {
+	case "csv":
+		txs, err = importer.ImportCSV(f, rules)
+	case "ofx", "qfx":
+		txs, err = importer.ImportOFX(f, rules)
+	case "qif":
+		txs, err = importer.ImportQIF(f, rules)
+	case "beancount":
+		txs, prices, err = beancount.Import(f)
+	case "json":
+		txs, err = pta.DecodeJSON(f)
+	default:
+		return fmt.Errorf("unknown import format '%s'", format)
+	}
+	if err != nil {
+		// report the entries that failed, keep the rest
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// the journal appended to, and deduplicated against
+	var journal pta.Journal
+	var existing []pta.Transaction
+	if *journalPath != "" {
+		journal, existing, err = pta.ParseJournal(*journalPath)
+		if err != nil {
+			return err
+		}
+	}
+	learned := existing
+	if *learnPath != "" && *learnPath != *journalPath {
+		_, learned, err = pta.ParseJournal(*learnPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *learnPath != "" || *journalPath != "" {
+		suggested := importer.NewCategorizer(learned, rules).Categorize(txs)
+		for i := range txs {
+			suggestion, found := suggested[i]
+			if !found {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", txs[i].Date.Format("2006/01/02"), txs[i].Description, suggestion)
+		}
+	}
+
+	if *journalPath == "" {
+		for _, price := range prices {
+			fmt.Print(pta.WritePrice(price))
+		}
+		if len(prices) > 0 {
+			fmt.Print("\r\n")
+		}
+		for _, tx := range txs {
+			fmt.Print(pta.WriteTransaction(tx))
+		}
+		return nil
+	}
+
+	txs, err = confirmDuplicates(existing, txs, *duplicates)
+	if err != nil {
+		return err
+	}
+	if len(prices) > 0 {
+		err = journal.AppendPrices(prices)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "appended %d prices to %s\n", len(prices), *journalPath)
+	}
+	if len(txs) == 0 {
+		fmt.Fprintln(os.Stderr, "no transactions to append")
+		return nil
+	}
+	err = journal.AppendTxs(txs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "appended %d transactions to %s\n", len(txs), *journalPath)
+	return nil
+}
+
+// drops the transactions likely already in the journal, unless
+// the user wants to keep them
+func confirmDuplicates(existing, txs []pta.Transaction, mode string) ([]pta.Transaction, error) {
+	if mode != "ask" && mode != "skip" && mode != "keep" {
+		return nil, fmt.Errorf("unknown -duplicates mode '%s'", mode)
+	}
+	fresh, found := importer.SplitDuplicates(existing, txs)
+	if mode == "keep" || len(found) == 0 {
+		return txs, nil
+	}
+
+	// the duplicates kept are appended last, as in the web import
+	skipped := 0
+	stdin := bufio.NewReader(os.Stdin)
+	for _, dup := range found {
+		if mode == "ask" {
+			fmt.Fprintf(os.Stderr, "likely duplicate (%s):\n%s\nalready in the journal:\n%s",
+				dup.Reason, pta.WriteTransaction(txs[dup.Index]), pta.WriteTransaction(dup.Existing))
+			fmt.Fprint(os.Stderr, "append it anyway? [y/N] ")
+			answer, _ := stdin.ReadString('\n')
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y") {
+				fresh = append(fresh, txs[dup.Index])
+				continue
+			}
+		}
+		skipped++
+	}
+	fmt.Fprintf(os.Stderr, "skipped %d likely duplicates\n", skipped)
+	return fresh, nil
+}
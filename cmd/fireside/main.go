@@ -1,7 +1,56 @@
 package main
 
-import "fireside/app"
+import (
+	"fireside/app"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"import", "import csv|ofx|qif|beancount|json [-rules FILE] [-learn JOURNAL] [-append JOURNAL [-duplicates ask|skip|keep]] STATEMENT", runImport},
+	{"export", "export beancount JOURNAL", runExport},
+	{"print", "print [-O journal|json|csv] JOURNAL", runPrint},
+	{"check", "check JOURNAL", runCheck},
+	{"balance", "balance [-value cost|then|end|market] [-exchange CODE] [-end DATE] JOURNAL", runBalance},
+	{"income", "income [-value cost|then|end|market] [-exchange CODE] [-end DATE] JOURNAL", runIncome},
+	{"fire", "fire [-end DATE] [-months N] JOURNAL", runFire},
+	{"simulate", "simulate [-strategy fixed|percentage|guardrails] [-runs N] [-seed N] [-history CSV] [-retire-in YEARS] [-withdrawal AMOUNT] [-rate PERCENT] [-years N] JOURNAL", runSimulate},
+	{"roi", "roi [-account REGEXP] [-period monthly|quarterly|yearly] [-exchange CODE] JOURNAL", runROI},
+	{"allocation", "allocation [-exchange CODE] [-end DATE] [-contribution AMOUNT] [-no-sell] JOURNAL", runAllocation},
+	{"budget", "budget [-period monthly|quarterly|yearly] [-rollover] JOURNAL", runBudget},
+	{"envelopes", "envelopes [-period monthly|quarterly|yearly] JOURNAL", runEnvelopes},
+	{"cashflow", "cashflow [-period monthly|quarterly|yearly] JOURNAL", runCashFlow},
+	{"close", "close -end DATE [-append] [-new JOURNAL] JOURNAL", runClose},
+}
 
 func main() {
-	app.Run()
+	if len(os.Args) < 2 {
+		app.Run()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			err := cmd.run(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  fireside %s\n", cmd.usage)
+	}
 }
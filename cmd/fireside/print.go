@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// prints the transactions of the journal, in the journal
+// format or for other tools
+func runPrint(args []string) error {
+	flags := flag.NewFlagSet("print", flag.ExitOnError)
+	output := flags.String("O", "journal", "Output format: journal, json or csv")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	_, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	switch *output {
+	case "journal":
+		for _, tx := range txs {
+			fmt.Print(pta.WriteTransaction(tx))
+		}
+		return nil
+	case "json":
+		return pta.EncodeJSON(os.Stdout, txs)
+	case "csv":
+		return pta.EncodeCSV(os.Stdout, txs)
+	}
+	return fmt.Errorf("unknown output format '%s'", *output)
+}
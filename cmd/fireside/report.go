@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func runBalance(args []string) error {
+	return runReport("balance", args)
+}
+
+func runIncome(args []string) error {
+	return runReport("income", args)
+}
+
+// prints the balance or income statement in a single commodity,
+// valued with the prices of the journal
+func runReport(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	value := flags.String("value", "cost", "Valuation: cost, then, end or market")
+	exchange := flags.String("exchange", "", "Commodity of the report, the journal currency by default")
+	end := flags.String("end", "", "Last date of the report (YYYY/MM/DD or YYYY-MM-DD)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	mode, err := pta.ParseValuationMode(*value)
+	if err != nil {
+		return err
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	v := pta.Valuation{
+		Mode:   mode,
+		Target: journal.DefaultCurrency,
+		Prices: pta.NewPriceHistory(journal.AllPrices()),
+	}
+	if *exchange != "" {
+		v.Target = pta.CommodityFromCode(*exchange)
+	}
+	if *end != "" {
+		v.End, err = parseEndDate(*end)
+		if err != nil {
+			return err
+		}
+		until := txs[:0]
+		for _, tx := range txs {
+			if !tx.Date.After(v.End) {
+				until = append(until, tx)
+			}
+		}
+		txs = until
+	} else {
+		for _, tx := range txs {
+			if tx.Date.After(v.End) {
+				v.End = tx.Date
+			}
+		}
+	}
+
+	var report pta.ValuedReport
+	total := "net worth"
+	if name == "balance" {
+		// a yearly file starts from its opening balances
+		report = v.BalanceStatement(pta.OpeningBalance(txs))
+	} else {
+		report = v.IncomeStatement(txs)
+		total = "net income"
+	}
+
+	width := 0
+	for _, line := range report.Lines {
+		width = max(width, len(line.Account))
+	}
+	var section string
+	for _, line := range report.Lines {
+		if line.Section != section {
+			section = line.Section
+			fmt.Println(section)
+		}
+		fmt.Printf("  %-*s  %16s", width, line.Account, line.Value.Str())
+		if line.Note != "" {
+			fmt.Printf("  ; %s", line.Note)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%s  %s\n", total, pta.Value{Decimal: report.Total, Commodity: report.Target}.Str())
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+	return nil
+}
+
+// the end dates are read like the dates of the journal,
+// 2024/12/31 or 2024-12-31
+func parseEndDate(end string) (time.Time, error) {
+	var scanner pta.Scanner
+	date, tail, err := scanner.ParseDate([]byte(end))
+	if err != nil || len(tail) != 0 {
+		return time.Time{}, fmt.Errorf("bad end date '%s'", end)
+	}
+	return date, nil
+}
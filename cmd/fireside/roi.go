@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/shopspring/decimal"
+)
+
+// prints the money-weighted and time-weighted returns of the
+// investment accounts, for each period and overall
+func runROI(args []string) error {
+	flags := flag.NewFlagSet("roi", flag.ExitOnError)
+	account := flags.String("account", "", "Regular expression of the investment accounts, fire:investments by default")
+	period := flags.String("period", "yearly", "Period: monthly, quarterly or yearly")
+	exchange := flags.String("exchange", "", "Commodity of the report, the journal currency by default")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	p, err := pta.ParsePeriod(*period)
+	if err != nil {
+		return err
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *account == "" {
+		*account = fire.InvestmentsPattern(journal)
+	}
+	target := journal.DefaultCurrency
+	if *exchange != "" {
+		target = pta.CommodityFromCode(*exchange)
+	}
+	report, err := pta.InvestmentReturn(txs, journal.AllPrices(), *account, p, target)
+	if err != nil {
+		return err
+	}
+
+	money := func(d decimal.Decimal) string {
+		return pta.Value{Decimal: d, Commodity: target}.Str()
+	}
+	percent := func(rate float64) string {
+		if math.IsNaN(rate) {
+			return "-"
+		}
+		if math.Abs(rate) < 0.00005 {
+			rate = 0 // not -0.00%
+		}
+		return fmt.Sprintf("%.2f%%", rate*100)
+	}
+	fmt.Printf("%-23s  %14s  %14s  %14s  %14s  %14s  %9s  %9s\n",
+		"period", "start", "contributions", "withdrawals", "income", "end", "xirr", "twr")
+	for _, perf := range append(report.Periods, report.Total) {
+		fmt.Printf("%s - %s  %14s  %14s  %14s  %14s  %14s  %9s  %9s\n",
+			perf.Start.Format("2006/01/02"), perf.End.Format("2006/01/02"),
+			money(perf.StartValue), money(perf.Contributions), money(perf.Withdrawals),
+			money(perf.Income), money(perf.EndValue), percent(perf.XIRR), percent(perf.TWR))
+	}
+	fmt.Printf("\nthe last line is the whole history, xirr is yearly, twr over the period\n")
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fireside/pkg/fire"
+	"fireside/pkg/pta"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runs the Monte Carlo simulation from the journal figures, the
+// flags override them
+func runSimulate(args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	strategy := flags.String("strategy", "fixed", "Withdrawal strategy: fixed, percentage or guardrails")
+	runs := flags.Int("runs", 10000, "Number of runs")
+	seed := flags.Uint64("seed", 1, "Seed of the random draws")
+	history := flags.String("history", "", "CSV of yearly returns to bootstrap (year,inflation,<class>,...), the bundled US returns by default, 'none' for the parametric model")
+	retireIn := flags.Int("retire-in", -1, "Years until retirement, the years to FI by default")
+	withdrawal := flags.Float64("withdrawal", -1, "Yearly withdrawal, the yearly expenses by default")
+	rate := flags.Float64("rate", -1, "Withdrawal rate for the percentage strategy, in %")
+	years := flags.Int("years", 0, "Years of retirement, fire:horizon by default")
+	end := flags.String("end", "", "Last date of the journal figures (YYYY/MM/DD or YYYY-MM-DD)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one journal file")
+	}
+	var endDate time.Time
+	if *end != "" {
+		var err error
+		endDate, err = parseEndDate(*end)
+		if err != nil {
+			return err
+		}
+	}
+	journal, txs, err := pta.ParseJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	config, err := fire.ConfigFromJournal(journal)
+	if err != nil {
+		return err
+	}
+	model, err := fire.ModelFromJournal(journal)
+	if err != nil {
+		return err
+	}
+	if *history == "" {
+		if value, found := journal.Setting(fire.HistorySetting); found {
+			*history = pta.ParsePath(filepath.Dir(journal.Filepath), value)
+		}
+	}
+	if *history == "none" {
+		model.History = nil
+	} else if *history != "" {
+		file, err := os.Open(*history)
+		if err != nil {
+			return err
+		}
+		model.History, err = fire.LoadHistory(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	report := fire.Plan(journal, txs, config, endDate)
+	sim := fire.SimulationFromPlan(report, model)
+	sim.Strategy, err = fire.ParseStrategy(*strategy)
+	if err != nil {
+		return err
+	}
+	sim.Runs, sim.Seed = *runs, *seed
+	if *retireIn >= 0 {
+		sim.YearsToRetire = *retireIn
+	}
+	if *withdrawal >= 0 {
+		sim.Withdrawal = *withdrawal
+	}
+	if *rate >= 0 {
+		sim.Rate = *rate / 100
+	}
+	if *years > 0 {
+		sim.Years = *years
+	}
+	result, err := fire.Simulate(sim)
+	if err != nil {
+		return err
+	}
+
+	returns := "parametric"
+	if len(model.History) > 0 {
+		returns = fmt.Sprintf("bootstrapped from %d years", len(model.History))
+	}
+	fmt.Printf("%d runs, %s returns, %s strategy\n", result.Runs, returns, *strategy)
+	fmt.Printf("portfolio %.0f, saving %.0f a year for %d years, then %d years of retirement\n",
+		sim.Portfolio, sim.Contribution, sim.YearsToRetire, sim.Years)
+	fmt.Printf("success   %.1f%%\n\n", result.Success*100)
+
+	fmt.Printf("%4s  %12s %12s %12s %12s %12s\n", "year", "p10", "p25", "p50", "p75", "p90")
+	for i, band := range result.Portfolio {
+		if (i+1)%5 != 0 && i != len(result.Portfolio)-1 {
+			continue
+		}
+		fmt.Printf("%4d  %12.0f %12.0f %12.0f %12.0f %12.0f\n", band.Year, band.P10, band.P25, band.P50, band.P75, band.P90)
+	}
+	if sim.Strategy != fire.FixedWithdrawal {
+		fmt.Printf("\nyearly spending\n")
+		for i, band := range result.Spending {
+			if (i+1)%5 != 0 && i != len(result.Spending)-1 {
+				continue
+			}
+			fmt.Printf("%4d  %12.0f %12.0f %12.0f %12.0f %12.0f\n", band.Year, band.P10, band.P25, band.P50, band.P75, band.P90)
+		}
+	}
+	fmt.Printf("\namounts in today's %s\n", config.Currency.Code)
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+	return nil
+}
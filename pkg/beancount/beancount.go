@@ -0,0 +1,125 @@
+// Package beancount converts between fireside journals and the
+// beancount syntax, so beancount tools (such as Fava) can be used
+// to explore a journal that is kept in fireside
+//
+// The two don't balance transactions the same way: fireside
+// balances each commodity separately, while beancount balances
+// the weight of the postings (converted by their cost or price).
+// The conversions add or reuse the costs to go from one to the
+// other, see Export and Import
+package beancount
+
+import (
+	"strings"
+	"unicode"
+)
+
+// the beancount account types, by fireside root account
+var roots = map[string]string{
+	"assets":      "Assets",
+	"asset":       "Assets",
+	"liabilities": "Liabilities",
+	"liability":   "Liabilities",
+	"equity":      "Equity",
+	"income":      "Income",
+	"revenue":     "Income",
+	"revenues":    "Income",
+	"expenses":    "Expenses",
+	"expense":     "Expenses",
+}
+
+// 'assets:tangerine:checking' to 'Assets:Tangerine:Checking', the
+// accounts outside of the five beancount types go under Assets
+func accountName(name string) string {
+	parts := strings.Split(name, ":")
+	root, found := roots[strings.ToLower(strings.TrimSpace(parts[0]))]
+	if found {
+		parts = parts[1:]
+	} else {
+		root = "Assets"
+	}
+	names := []string{root}
+	for _, part := range parts {
+		names = append(names, accountComponent(part))
+	}
+	return strings.Join(names, ":")
+}
+
+// components start with an upper case letter or a digit,
+// followed by letters, digits and dashes
+func accountComponent(part string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(part) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	component := []rune(sb.String())
+	if len(component) == 0 {
+		return "X"
+	}
+	if !unicode.IsDigit(component[0]) {
+		component[0] = unicode.ToUpper(component[0])
+		if !unicode.IsUpper(component[0]) {
+			return "X" + string(component)
+		}
+	}
+	return string(component)
+}
+
+// currencies are upper case, starting with a letter, and
+// ending with a letter or digit
+func currencyName(code string) string {
+	code = strings.ToUpper(strings.Trim(code, `"'`))
+	var sb strings.Builder
+	for _, r := range code {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+			(sb.Len() > 0 && strings.ContainsRune("'._-", r)) {
+			sb.WriteRune(r)
+		}
+	}
+	name := strings.TrimRight(sb.String(), "'._-")
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		name = "C" + name
+	}
+	if len(name) > 24 {
+		name = name[:24]
+	}
+	return name
+}
+
+// metadata keys start with a lower case letter
+func metaKey(key string) string {
+	var sb strings.Builder
+	for _, r := range key {
+		if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "x" + name
+	}
+	return name
+}
+
+func tagName(tag string) string {
+	var sb strings.Builder
+	for _, r := range tag {
+		if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_/.", r)) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,189 @@
+package beancount
+
+import (
+	"fireside/pkg/pta"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestImport(t *testing.T) {
+	f, err := os.Open("./test/sample.beancount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	txs, prices, err := Import(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 1 || prices[0].Code != "VTI" || !prices[0].Decimal.Equal(decimal.RequireFromString("245.10")) {
+		t.Errorf("unexpected prices %+v", prices)
+	}
+	if len(txs) != 4 {
+		t.Fatalf("expected 4 transactions, got %d", len(txs))
+	}
+
+	pay := txs[0]
+	if pay.Description != "ACME | Payroll #q1 #work" || !pay.Cleared || pay.Code != "101" {
+		t.Errorf("unexpected transaction %+v", pay)
+	}
+	if pay.Meta["links"] != "pay-01" {
+		t.Errorf("unexpected metadata %v", pay.Meta)
+	}
+	if pay.Postings[1].Account != "income:salary" || !pay.Postings[1].Amount.Equal(decimal.New(-2500, 0)) {
+		t.Errorf("unexpected posting without amount %+v", pay.Postings[1])
+	}
+
+	groceries := txs[1]
+	if !groceries.Pending || groceries.Description != "Metro #q1" || len(groceries.Meta) != 0 {
+		t.Errorf("unexpected transaction %+v", groceries)
+	}
+
+	// balanced by commodity with the trading account
+	buy := txs[2]
+	if len(buy.Postings) != 4 || buy.Postings[2].Account != tradingAccount || buy.Postings[3].Account != tradingAccount {
+		t.Fatalf("expected trading postings, got %+v", buy.Postings)
+	}
+	if !buy.Postings[0].UnitValue.Decimal.Equal(decimal.New(240, 0)) {
+		t.Errorf("unexpected cost %+v", buy.Postings[0].UnitValue)
+	}
+
+	balance := txs[3]
+	if balance.Date.Format("2006-01-02") != "2024-01-31" || balance.Postings[0].Assertion == nil ||
+		!balance.Postings[0].Assertion.Decimal.Equal(decimal.RequireFromString("1494.80")) {
+		t.Errorf("unexpected balance assertion %+v", balance)
+	}
+
+	// the output is a valid journal, with passing assertions
+	var sb strings.Builder
+	for _, price := range prices {
+		sb.WriteString(pta.WritePrice(price))
+	}
+	for _, tx := range txs {
+		sb.WriteString(pta.WriteTransaction(tx))
+	}
+	journal, parsed, err := parseJournal(sb.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(txs) || len(journal.Prices) != 1 {
+		t.Errorf("expected %d transactions and a price, got %d and %d", len(txs), len(parsed), len(journal.Prices))
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	in := `2024-01-01 open Assets:Cash
+2024-01-02 pad Assets:Cash Equity:Opening-Balances
+2024-01-03 * "Coffee"
+  Expenses:Coffee  (2 + 1.50) USD
+  Assets:Cash
+2024-01-04 * "Lunch"
+  Expenses:Food  12.00 USD
+  Assets:Cash  -10.00 USD
+2024-01-05 * "Book"
+  Expenses:Books  20.00 USD
+  Assets:Cash
+`
+	txs, _, err := Import(strings.NewReader(in))
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, line := range []string{"beancount:2:", "beancount:4:", "beancount:6:"} {
+		if !strings.Contains(err.Error(), line) {
+			t.Errorf("expected an error on %s got: %v", line, err)
+		}
+	}
+	if len(txs) != 1 || txs[0].Description != "Book" {
+		t.Errorf("expected the other transactions, got %+v", txs)
+	}
+}
+
+func TestExport(t *testing.T) {
+	journal, txs, err := parseJournal(`
+P 2024/01/15 VTI $245.10
+
+2024/01/02 * (101) ACME payroll #work
+	; memo: january
+	assets:bank:checking  $2,500.00
+	income:salary
+
+2024/01/10 Buy VTI
+	assets:brokerage      4 VTI @ $240.00
+	equity:trading       -4 VTI
+	equity:trading        $960.00
+	assets:bank:checking  -$960.00 = $1,540.00
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	err = Export(&sb, journal, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, line := range []string{
+		`option "operating_currency" "USD"`,
+		"2024-01-02 open Assets:Bank:Checking",
+		"2024-01-10 open Equity:Trading",
+		"2024-01-15 price VTI 245.1 USD",
+		`2024-01-02 * "ACME payroll" #work`,
+		`  code: "101"`,
+		`  memo: "january"`,
+		"  Assets:Brokerage  4 VTI {240 USD}",
+		"  Equity:Trading  -4 VTI @ 240 USD",
+		"2024-01-11 balance Assets:Bank:Checking  1540 USD",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("expected '%s' in:\n%s", line, out)
+		}
+	}
+
+	// and back
+	imported, prices, err := Import(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 1 || len(imported) != 3 {
+		t.Fatalf("expected a price and 3 transactions, got %d and %d", len(prices), len(imported))
+	}
+	if imported[0].Code != "101" || imported[0].Meta["memo"] != "january" ||
+		imported[0].Description != "ACME payroll #work" {
+		t.Errorf("unexpected transaction %+v", imported[0])
+	}
+	if len(imported[1].Postings) != 4 {
+		t.Errorf("expected the trading postings to balance, got %+v", imported[1].Postings)
+	}
+}
+
+func TestAccountName(t *testing.T) {
+	for in, out := range map[string]string{
+		"assets:bank:checking":  "Assets:Bank:Checking",
+		"expenses:eating out":   "Expenses:Eating-out",
+		"revenue:consulting":    "Income:Consulting",
+		"savings:emergency":     "Assets:Savings:Emergency",
+		"expenses:401k":         "Expenses:401k",
+		"liabilities:visa card": "Liabilities:Visa-card",
+	} {
+		if got := accountName(in); got != out {
+			t.Errorf("expected %s for %s, got %s", out, in, got)
+		}
+	}
+}
+
+func parseJournal(in string) (pta.Journal, []pta.Transaction, error) {
+	d := pta.NewDecoder(strings.NewReader(in))
+	var txs []pta.Transaction
+	for tx, err := range d.All() {
+		if err != nil {
+			return pta.Journal{}, nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return *d.Journal(), txs, nil
+}
@@ -0,0 +1,173 @@
+package beancount
+
+import (
+	"bufio"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Export writes the journal and its transactions in beancount syntax:
+//
+//   - an open directive for each account, dated at its first use
+//   - a price directive for each 'P' price
+//   - the transactions, with their flag, tags and metadata (the code
+//     is kept as 'code' metadata, and the 'links' metadata as links)
+//   - a balance directive, on the next day, for each balance assertion
+//
+// Beancount balances the weight of the postings, so a unit value '@'
+// is carried over to the other postings of the same commodity in the
+// transaction (such as the equity:trading counterparts). Positive
+// amounts are written at cost '{}', and negative ones at price '@',
+// which doesn't need a matching lot to reduce
+func Export(w io.Writer, journal pta.Journal, txs []pta.Transaction) error {
+	bw := bufio.NewWriter(w)
+
+	if journal.DefaultCurrency.Code != "" {
+		fmt.Fprintf(bw, "option \"operating_currency\" %s\n\n", quote(currencyName(journal.DefaultCurrency.Code)))
+	}
+
+	opened := make(map[string]time.Time)
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			name := accountName(post.Account)
+			if date, found := opened[name]; !found || tx.Date.Before(date) {
+				opened[name] = tx.Date
+			}
+		}
+	}
+	accounts := make([]string, 0, len(opened))
+	for name := range opened {
+		accounts = append(accounts, name)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		a, b := opened[accounts[i]], opened[accounts[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return accounts[i] < accounts[j]
+	})
+	for _, name := range accounts {
+		fmt.Fprintf(bw, "%s open %s\n", opened[name].Format(time.DateOnly), name)
+	}
+	if len(accounts) > 0 {
+		bw.WriteString("\n")
+	}
+
+	prices := journal.AllPrices()
+	for _, price := range prices {
+		fmt.Fprintf(bw, "%s price %s %s\n", price.Date.Format(time.DateOnly),
+			currencyName(price.Code), amount(price.Decimal, price.Commodity))
+	}
+	if len(prices) > 0 {
+		bw.WriteString("\n")
+	}
+
+	for _, tx := range txs {
+		writeTransaction(bw, tx)
+	}
+	return bw.Flush()
+}
+
+func writeTransaction(w *bufio.Writer, tx pta.Transaction) {
+	// assertions on their own, without moving money
+	moves := slices.ContainsFunc(tx.Postings, func(post pta.Posting) bool {
+		return !post.Amount.IsZero() || post.Assertion == nil
+	})
+
+	if moves {
+		flag := "txn"
+		if tx.Cleared {
+			flag = "*"
+		} else if tx.Pending {
+			flag = "!"
+		}
+		fmt.Fprintf(w, "%s %s", tx.Date.Format(time.DateOnly), flag)
+		// 'payee | narration', as imported
+		payee, desc, found := strings.Cut(narration(tx.Description), " | ")
+		if found {
+			fmt.Fprintf(w, " %s %s", quote(payee), quote(desc))
+		} else {
+			fmt.Fprintf(w, " %s", quote(payee))
+		}
+		for _, tag := range tx.Tags {
+			if tag = tagName(tag); tag != "" {
+				fmt.Fprintf(w, " #%s", tag)
+			}
+		}
+		for _, link := range strings.Fields(tx.Meta["links"]) {
+			if link = tagName(link); link != "" {
+				fmt.Fprintf(w, " ^%s", link)
+			}
+		}
+		w.WriteString("\n")
+
+		if tx.Code != "" {
+			fmt.Fprintf(w, "  code: %s\n", quote(tx.Code))
+		}
+		keys := make([]string, 0, len(tx.Meta))
+		for key := range tx.Meta {
+			if key != "links" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s: %s\n", metaKey(key), quote(tx.Meta[key]))
+		}
+
+		// the cost of each commodity with a unit value
+		costs := make(map[string]pta.Value)
+		for _, post := range tx.Postings {
+			if !post.UnitValue.Decimal.IsZero() {
+				if _, found := costs[post.Commodity.Code]; !found {
+					costs[post.Commodity.Code] = post.UnitValue
+				}
+			}
+		}
+
+		for _, post := range tx.Postings {
+			fmt.Fprintf(w, "  %s  %s", accountName(post.Account), amount(post.Amount, post.Commodity))
+			cost := post.UnitValue
+			if cost.Decimal.IsZero() {
+				cost = costs[post.Commodity.Code]
+			}
+			if !cost.Decimal.IsZero() {
+				if post.Amount.IsNegative() {
+					fmt.Fprintf(w, " @ %s", amount(cost.Decimal, cost.Commodity))
+				} else {
+					fmt.Fprintf(w, " {%s}", amount(cost.Decimal, cost.Commodity))
+				}
+			}
+			w.WriteString("\n")
+		}
+		w.WriteString("\n")
+	}
+
+	// beancount checks the balance at the start of the day
+	for _, post := range tx.Postings {
+		if post.Assertion != nil {
+			fmt.Fprintf(w, "%s balance %s  %s\n\n", tx.Date.AddDate(0, 0, 1).Format(time.DateOnly),
+				accountName(post.Account), amount(post.Assertion.Decimal, post.Assertion.Commodity))
+		}
+	}
+}
+
+// the tags are written after the narration
+func narration(desc string) string {
+	words := strings.Fields(desc)
+	words = slices.DeleteFunc(words, func(word string) bool {
+		return len(word) > 1 && word[0] == '#'
+	})
+	return strings.Join(words, " ")
+}
+
+func amount(d decimal.Decimal, com pta.Commodity) string {
+	return d.String() + " " + currencyName(com.Code)
+}
@@ -0,0 +1,535 @@
+package beancount
+
+import (
+	"bufio"
+	"errors"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// the journal only balances amounts of the same commodity, so the
+// conversions at cost or price go through this account
+const tradingAccount = "equity:trading"
+
+// directives without an equivalent in the journal
+var ignored = map[string]bool{
+	"open": true, "close": true, "commodity": true, "note": true, "document": true,
+	"event": true, "query": true, "custom": true,
+}
+
+type beanImport struct {
+	row    int
+	txs    []pta.Transaction
+	prices []pta.Price
+	errs   []error
+	tags   []string // from pushtag
+
+	// the transaction being read
+	tx       *pta.Transaction
+	txRow    int
+	failed   bool                       // a posting couldn't be read
+	weights  map[string]decimal.Decimal // by currency
+	auto     int                        // the posting without amount, or -1
+	trading  bool                       // at cost or price
+	skipping bool                       // metadata of an ignored directive
+}
+
+// Import reads the transactions and prices of a beancount file:
+//
+//   - the transactions keep their flag, tags, links and metadata
+//     (links in the 'links' metadata, 'code' as the transaction code)
+//   - the posting without amount gets the remaining weight
+//   - the postings at cost '{}' or price '@' get that unit value, and
+//     equity:trading postings to balance each commodity
+//   - the balance directives become balance assertions at the end
+//     of the previous day
+//
+// Lots are not booked, so a reduction at an empty cost '{}' uses its
+// price, and the capital gains are not computed. The open, close,
+// commodity, option and similar directives are skipped, and pad
+// directives are reported as errors. The directives that can't be
+// converted are reported together in the error, the other
+// transactions are still returned
+func Import(r io.Reader) ([]pta.Transaction, []pta.Price, error) {
+	imp := beanImport{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		imp.row++
+		err := imp.line(scanner.Text())
+		if err != nil {
+			imp.errs = append(imp.errs, fmt.Errorf("beancount:%d: %s", imp.row, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	imp.endTransaction()
+
+	// the balance assertions after the transactions of the day
+	sort.SliceStable(imp.txs, func(i, j int) bool {
+		a, b := imp.txs[i], imp.txs[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return !assertionOnly(a) && assertionOnly(b)
+	})
+	slices.SortStableFunc(imp.prices, func(a, b pta.Price) int {
+		return a.Date.Compare(b.Date)
+	})
+	return imp.txs, imp.prices, errors.Join(imp.errs...)
+}
+
+func (imp *beanImport) line(line string) error {
+	line = stripComment(line)
+	if strings.TrimSpace(line) == "" {
+		imp.endTransaction()
+		return nil
+	}
+
+	if unicode.IsSpace(rune(line[0])) {
+		line = strings.TrimSpace(line)
+		switch {
+		case imp.skipping:
+			return nil
+		case imp.tx == nil:
+			return fmt.Errorf("unexpected indented line: '%s'", line)
+		case isMeta(line):
+			// posting metadata is dropped
+			if len(imp.tx.Postings) == 0 {
+				key, val := splitMeta(line)
+				imp.addMeta(key, val)
+			}
+			return nil
+		default:
+			err := imp.posting(line)
+			if err != nil {
+				imp.failed = true
+			}
+			return err
+		}
+	}
+
+	imp.endTransaction()
+	imp.skipping = true
+
+	tokens, err := tokenize(line)
+	if err != nil {
+		return err
+	}
+	switch tokens[0] {
+	case "option", "plugin", "include":
+		return nil
+	case "pushtag":
+		if len(tokens) != 2 || !strings.HasPrefix(tokens[1], "#") {
+			return fmt.Errorf("pushtag must be followed by a tag")
+		}
+		imp.tags = append(imp.tags, tokens[1][1:])
+		return nil
+	case "poptag":
+		if len(tokens) != 2 || !strings.HasPrefix(tokens[1], "#") {
+			return fmt.Errorf("poptag must be followed by a tag")
+		}
+		i := slices.Index(imp.tags, tokens[1][1:])
+		if i == -1 {
+			return fmt.Errorf("poptag of a tag not pushed: '%s'", tokens[1])
+		}
+		imp.tags = slices.Delete(imp.tags, i, i+1)
+		return nil
+	}
+	// org-mode headings
+	if strings.ContainsRune("*#", rune(line[0])) {
+		return nil
+	}
+
+	date, err := time.Parse(time.DateOnly, strings.ReplaceAll(tokens[0], "/", "-"))
+	if err != nil {
+		return fmt.Errorf("unknown directive: '%s'", line)
+	}
+	if len(tokens) < 2 {
+		return fmt.Errorf("missing directive after the date")
+	}
+
+	directive := tokens[1]
+	switch {
+	case ignored[directive]:
+		return nil
+	case directive == "pad":
+		return fmt.Errorf("pad directives are not supported, use a transaction instead")
+	case directive == "price":
+		return imp.price(date, tokens[2:])
+	case directive == "balance":
+		return imp.balance(date, tokens[2:])
+	case directive == "txn" || len(directive) == 1:
+		return imp.transaction(date, directive, tokens[2:])
+	}
+	return fmt.Errorf("unknown directive: '%s'", directive)
+}
+
+// 'price VTI 245.10 USD'
+func (imp *beanImport) price(date time.Time, tokens []string) error {
+	if len(tokens) != 3 {
+		return fmt.Errorf("price must be followed by a commodity and an amount")
+	}
+	amount, err := parseNumber(tokens[1])
+	if err != nil {
+		return err
+	}
+	imp.prices = append(imp.prices, pta.Price{
+		Date:  date,
+		Code:  tokens[0],
+		Value: pta.Value{Decimal: amount, Commodity: pta.CommodityFromCode(tokens[2])},
+	})
+	return nil
+}
+
+// 'balance Assets:Checking 1200.00 USD' checks the balance at the
+// start of the day
+func (imp *beanImport) balance(date time.Time, tokens []string) error {
+	if len(tokens) < 3 {
+		return fmt.Errorf("balance must be followed by an account and an amount")
+	}
+	amount, err := parseNumber(tokens[1])
+	if err != nil {
+		return err
+	}
+	commodity := pta.CommodityFromCode(tokens[2])
+	imp.txs = append(imp.txs, pta.Transaction{
+		Date:        date.AddDate(0, 0, -1),
+		Description: "balance",
+		Postings: []pta.Posting{{
+			Account:   accountFrom(tokens[0]),
+			Lot:       pta.Lot{Commodity: commodity},
+			Assertion: &pta.Value{Decimal: amount, Commodity: commodity},
+		}},
+	})
+	return nil
+}
+
+// '* "Payee" "Narration" #tag ^link'
+func (imp *beanImport) transaction(date time.Time, flag string, tokens []string) error {
+	tx := pta.Transaction{
+		Date:    date,
+		Cleared: flag == "*",
+		Pending: flag == "!",
+	}
+
+	var texts, links []string
+	tags := slices.Clone(imp.tags)
+	for _, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, `"`):
+			texts = append(texts, unquote(token))
+		case strings.HasPrefix(token, "#"):
+			tags = append(tags, token[1:])
+		case strings.HasPrefix(token, "^"):
+			links = append(links, token[1:])
+		default:
+			return fmt.Errorf("unexpected token in transaction: '%s'", token)
+		}
+	}
+	switch len(texts) {
+	case 0:
+	case 1:
+		tx.Description = texts[0]
+	case 2:
+		tx.Description = texts[1]
+		if texts[0] != "" {
+			tx.Description = texts[0] + " | " + texts[1]
+		}
+	default:
+		return fmt.Errorf("too many strings in transaction")
+	}
+
+	// the journal reads the tags from the description
+	for _, tag := range tags {
+		if !slices.Contains(tx.Tags, tag) {
+			tx.Tags = append(tx.Tags, tag)
+		}
+		if !slices.Contains(strings.Fields(tx.Description), "#"+tag) {
+			tx.Description = strings.TrimSpace(tx.Description + " #" + tag)
+		}
+	}
+
+	imp.tx = &tx
+	imp.txRow = imp.row
+	imp.failed = false
+	imp.skipping = false
+	imp.weights = make(map[string]decimal.Decimal)
+	imp.auto = -1
+	imp.trading = false
+	if len(links) > 0 {
+		imp.addMeta("links", strings.Join(links, " "))
+	}
+	return nil
+}
+
+func (imp *beanImport) addMeta(key, val string) {
+	if key == "code" {
+		imp.tx.Code = val
+		return
+	}
+	if imp.tx.Meta == nil {
+		imp.tx.Meta = make(map[string]string)
+	}
+	imp.tx.Meta[key] = val
+}
+
+// '[flag] Account [amount CUR] [{cost CUR}] [@ price CUR]'
+func (imp *beanImport) posting(line string) error {
+	if len(line) > 1 && !unicode.IsLetter(rune(line[0])) && unicode.IsSpace(rune(line[1])) {
+		line = strings.TrimSpace(line[1:])
+	}
+	account, rest, _ := strings.Cut(line, " ")
+	post := pta.Posting{Account: accountFrom(account)}
+	rest = strings.TrimSpace(rest)
+
+	if rest == "" {
+		if imp.auto != -1 {
+			return fmt.Errorf("more than one posting without amount")
+		}
+		imp.auto = len(imp.tx.Postings)
+		imp.tx.Postings = append(imp.tx.Postings, post)
+		return nil
+	}
+
+	var costSpec, priceSpec string
+	var hasCost, totalCost, totalPrice bool
+	if i := strings.Index(rest, "@"); i != -1 {
+		rest, priceSpec = rest[:i], rest[i+1:]
+		if strings.HasPrefix(priceSpec, "@") {
+			priceSpec, totalPrice = priceSpec[1:], true
+		}
+	}
+	if i := strings.Index(rest, "{"); i != -1 {
+		end := strings.LastIndex(rest, "}")
+		if end < i {
+			return fmt.Errorf("missing '}' after the cost")
+		}
+		rest, costSpec, hasCost = rest[:i], rest[i+1:end], true
+		if strings.HasPrefix(costSpec, "{") {
+			costSpec, totalCost = strings.Trim(costSpec, "{}"), true
+		}
+	}
+
+	amount, commodity, err := parseAmount(rest)
+	if err != nil {
+		return err
+	}
+	post.Amount, post.Commodity = amount, commodity
+
+	// the unit value, by cost first
+	var unit *pta.Value
+	total := false
+	if hasCost {
+		// the lot date and label are ignored
+		for _, part := range strings.Split(costSpec, ",") {
+			if value, commodity, err := parseAmount(part); err == nil {
+				unit = &pta.Value{Decimal: value, Commodity: commodity}
+				total = totalCost
+				break
+			}
+		}
+	}
+	if unit == nil && priceSpec != "" {
+		value, commodity, err := parseAmount(priceSpec)
+		if err != nil {
+			return err
+		}
+		unit = &pta.Value{Decimal: value, Commodity: commodity}
+		total = totalPrice
+	}
+	if hasCost && unit == nil {
+		return fmt.Errorf("cost without amount needs a price: '%s'", line)
+	}
+
+	weight := pta.Value{Decimal: amount, Commodity: commodity}
+	if unit != nil {
+		if total && !amount.IsZero() {
+			unit.Decimal = unit.Decimal.Div(amount.Abs())
+		}
+		post.UnitValue = *unit
+		weight = pta.Value{Decimal: amount.Mul(unit.Decimal), Commodity: unit.Commodity}
+		imp.trading = true
+	}
+	imp.weights[weight.Code] = imp.weights[weight.Code].Add(weight.Decimal)
+	imp.tx.Postings = append(imp.tx.Postings, post)
+	return nil
+}
+
+// the transaction is complete: fills the posting without amount
+// and balances the commodities. The transactions with errors are
+// dropped
+func (imp *beanImport) endTransaction() {
+	tx := imp.tx
+	if tx == nil || imp.failed {
+		imp.tx = nil
+		return
+	}
+	imp.tx = nil
+
+	if imp.auto != -1 {
+		codes := make([]string, 0, len(imp.weights))
+		for code, weight := range imp.weights {
+			if !weight.IsZero() {
+				codes = append(codes, code)
+			}
+		}
+		sort.Strings(codes)
+		auto := tx.Postings[imp.auto]
+		var postings []pta.Posting
+		for _, code := range codes {
+			post := auto
+			post.Amount = imp.weights[code].Neg()
+			post.Commodity = pta.CommodityFromCode(code)
+			postings = append(postings, post)
+			imp.weights[code] = decimal.Zero
+		}
+		tx.Postings = slices.Replace(tx.Postings, imp.auto, imp.auto+1, postings...)
+	}
+	for code, weight := range imp.weights {
+		if !weight.IsZero() {
+			imp.errs = append(imp.errs, fmt.Errorf("beancount:%d: transaction does not balance: %s %s left",
+				imp.txRow, weight, code))
+			return
+		}
+	}
+
+	if imp.trading {
+		sums := make(map[string]decimal.Decimal)
+		for _, post := range tx.Postings {
+			sums[post.Commodity.Code] = sums[post.Commodity.Code].Add(post.Amount)
+		}
+		codes := make([]string, 0, len(sums))
+		for code := range sums {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			if !sums[code].IsZero() {
+				tx.Postings = append(tx.Postings, pta.Posting{
+					Account: tradingAccount,
+					Lot:     pta.Lot{Amount: sums[code].Neg(), Commodity: pta.CommodityFromCode(code)},
+				})
+			}
+		}
+	}
+
+	imp.txs = append(imp.txs, *tx)
+}
+
+// 'Assets:Tangerine:Checking' to 'assets:tangerine:checking'
+func accountFrom(name string) string {
+	return strings.ToLower(name)
+}
+
+func assertionOnly(tx pta.Transaction) bool {
+	for _, post := range tx.Postings {
+		if post.Assertion == nil || !post.Amount.IsZero() {
+			return false
+		}
+	}
+	return len(tx.Postings) > 0
+}
+
+// '1,234.50 USD'
+func parseAmount(val string) (decimal.Decimal, pta.Commodity, error) {
+	fields := strings.Fields(val)
+	if len(fields) != 2 {
+		return decimal.Zero, pta.Commodity{}, fmt.Errorf("bad amount: '%s'", strings.TrimSpace(val))
+	}
+	amount, err := parseNumber(fields[0])
+	if err != nil {
+		return decimal.Zero, pta.Commodity{}, err
+	}
+	return amount, pta.CommodityFromCode(fields[1]), nil
+}
+
+func parseNumber(val string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.ReplaceAll(val, ",", ""))
+	if err != nil {
+		return amount, fmt.Errorf("bad number (expressions are not supported): '%s'", val)
+	}
+	return amount, nil
+}
+
+// metadata are 'key: value' lines, the keys start with a lower
+// case letter
+func isMeta(line string) bool {
+	key, _, found := strings.Cut(line, ":")
+	return found && key != "" && key[0] >= 'a' && key[0] <= 'z' &&
+		!strings.ContainsAny(key, " \t")
+}
+
+func splitMeta(line string) (key, val string) {
+	key, val, _ = strings.Cut(line, ":")
+	val = strings.TrimSpace(val)
+	if strings.HasPrefix(val, `"`) {
+		val = unquote(val)
+	}
+	return key, val
+}
+
+// comments start with ';', outside of the strings
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return strings.TrimRightFunc(line[:i], unicode.IsSpace)
+			}
+		}
+	}
+	return line
+}
+
+// splits on spaces, the strings are kept whole with their quotes
+func tokenize(line string) (tokens []string, err error) {
+	for i := 0; i < len(line); {
+		if unicode.IsSpace(rune(line[i])) {
+			i++
+			continue
+		}
+		start := i
+		if line[i] == '"' {
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("missing closing quote: '%s'", line[start:])
+			}
+			i++
+		} else {
+			for i < len(line) && !unicode.IsSpace(rune(line[i])) {
+				i++
+			}
+		}
+		tokens = append(tokens, line[start:i])
+	}
+	return tokens, nil
+}
+
+func unquote(s string) string {
+	if val, err := strconv.Unquote(s); err == nil {
+		return val
+	}
+	return strings.Trim(s, `"`)
+}
@@ -0,0 +1,220 @@
+package fire
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The asset class of a commodity is the 'class' metadata of its
+// commodity directive, the currencies without one are cash:
+//
+//	commodity VTI
+//	    ; class: us-equity
+//
+// The target allocation is a setting of the same form as the
+// allocation of the simulation, which it defaults to:
+//
+//	fire:target us-equity 60%, intl-equity 20%, bonds 20%
+
+const (
+	ClassMeta     = "class"
+	TargetSetting = "fire:target" // fire:allocation by default
+)
+
+// the class of the commodities without metadata, other than currencies
+const Unclassified = "unclassified"
+
+type ClassAllocation struct {
+	Class       string
+	Commodities []string
+	Value       decimal.Decimal
+	Share       float64 // of the total
+	Target      float64 // zero without a target
+}
+
+type Allocation struct {
+	Currency pta.Commodity
+	Date     time.Time
+	Total    decimal.Decimal
+	Classes  []ClassAllocation // the targets first, in their order
+	Targeted bool              // whether the journal has a target
+	Warnings []string
+}
+
+// Trade is a purchase (positive amount) or a sale (negative) of
+// an asset class
+type Trade struct {
+	Class  string
+	Amount decimal.Decimal
+	Share  float64 // of the class, after the trades
+}
+
+// TargetFromJournal reads the target allocation, nil without one
+func TargetFromJournal(journal pta.Journal) ([]AssetClass, error) {
+	if value, found := journal.Setting(TargetSetting); found {
+		return parseWeights(TargetSetting, value)
+	}
+	if value, found := journal.Setting(AllocationSetting); found {
+		return parseWeights(AllocationSetting, value)
+	}
+	return nil, nil
+}
+
+// ComputeAllocation values the asset accounts at the date (the
+// last transaction or price when zero) with the market prices, and
+// groups them by asset class. The commodities without a price are
+// left out with a warning
+func ComputeAllocation(journal pta.Journal, txs []pta.Transaction, currency pta.Commodity, date time.Time) (Allocation, error) {
+	targets, err := TargetFromJournal(journal)
+	if err != nil {
+		return Allocation{}, err
+	}
+	var until []pta.Transaction
+	for _, tx := range txs {
+		if date.IsZero() || !tx.Date.After(date) {
+			until = append(until, tx)
+		}
+	}
+	prices := append(journal.AllPrices(), pta.TransactionPrices(until)...)
+	if date.IsZero() {
+		for _, tx := range until {
+			if tx.Date.After(date) {
+				date = tx.Date
+			}
+		}
+		for _, price := range prices {
+			if price.Date.After(date) {
+				date = price.Date
+			}
+		}
+	}
+	allocation := Allocation{Currency: currency, Date: date, Targeted: targets != nil}
+
+	balances := make(map[string]decimal.Decimal)
+	commodities := make(map[string]pta.Commodity)
+	for _, tx := range until {
+		for _, post := range tx.Postings {
+			if !strings.Contains(post.Account, "asset") {
+				continue
+			}
+			balances[post.Commodity.Code] = balances[post.Commodity.Code].Add(post.Amount)
+			commodities[post.Commodity.Code] = post.Commodity
+		}
+	}
+	history := pta.NewPriceHistory(prices)
+
+	for _, target := range targets {
+		allocation.Classes = append(allocation.Classes, ClassAllocation{Class: target.Name, Target: target.Weight})
+	}
+	codes := make([]string, 0, len(balances))
+	for code := range balances {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		amount := balances[code]
+		if amount.IsZero() {
+			continue
+		}
+		value := amount
+		if code != currency.Code {
+			var found bool
+			value, _, found = history.Convert(amount, code, currency.Code, date)
+			if !found {
+				allocation.Warnings = append(allocation.Warnings,
+					fmt.Sprintf("no price for %s in %s, left out of the allocation", code, currency.Code))
+				continue
+			}
+		}
+
+		class, found := journal.CommodityMeta(code, ClassMeta)
+		switch {
+		case found:
+		case commodities[code].Type == pta.CURRENCY:
+			class = "cash"
+		default:
+			class = Unclassified
+			allocation.Warnings = append(allocation.Warnings,
+				fmt.Sprintf("%s has no asset class, set '%s:' on its commodity directive", code, ClassMeta))
+		}
+		i := slices.IndexFunc(allocation.Classes, func(c ClassAllocation) bool { return c.Class == class })
+		if i == -1 {
+			allocation.Classes = append(allocation.Classes, ClassAllocation{Class: class})
+			i = len(allocation.Classes) - 1
+		}
+		allocation.Classes[i].Commodities = append(allocation.Classes[i].Commodities, code)
+		allocation.Classes[i].Value = allocation.Classes[i].Value.Add(value)
+		allocation.Total = allocation.Total.Add(value)
+	}
+
+	// the classes without a target, by value
+	slices.SortStableFunc(allocation.Classes[len(targets):], func(a, b ClassAllocation) int {
+		return b.Value.Cmp(a.Value)
+	})
+	if allocation.Total.IsPositive() {
+		for i := range allocation.Classes {
+			allocation.Classes[i].Share = allocation.Classes[i].Value.Div(allocation.Total).InexactFloat64()
+		}
+	}
+	return allocation, nil
+}
+
+// Rebalance proposes the trades bringing the allocation, with the
+// new contribution, to its target. The classes without a target
+// are sold. Without selling, only the contribution is invested, in
+// the classes below their target in proportion to the gap
+func Rebalance(allocation Allocation, contribution decimal.Decimal, sell bool) []Trade {
+	if !allocation.Targeted {
+		return nil
+	}
+	total := allocation.Total.Add(contribution)
+	gaps := make([]decimal.Decimal, len(allocation.Classes))
+	var sum decimal.Decimal
+	for i, class := range allocation.Classes {
+		gaps[i] = decimal.NewFromFloat(class.Target).Mul(total).Sub(class.Value)
+		if gaps[i].IsPositive() {
+			sum = sum.Add(gaps[i])
+		}
+	}
+
+	amounts := make([]decimal.Decimal, len(gaps))
+	for i, gap := range gaps {
+		switch {
+		case sell:
+			amounts[i] = gap.Round(2)
+		case gap.IsPositive() && contribution.IsPositive():
+			amounts[i] = contribution.Mul(gap).Div(sum).Round(2)
+		}
+	}
+	// the rounding goes to the largest trade, so that the trades
+	// add up to the contribution
+	rest := contribution
+	largest := -1
+	for i, amount := range amounts {
+		rest = rest.Sub(amount)
+		if largest == -1 || amount.Abs().GreaterThan(amounts[largest].Abs()) {
+			largest = i
+		}
+	}
+	if largest != -1 && !amounts[largest].IsZero() {
+		amounts[largest] = amounts[largest].Add(rest)
+	}
+
+	var trades []Trade
+	for i, class := range allocation.Classes {
+		if amounts[i].IsZero() {
+			continue
+		}
+		trade := Trade{Class: class.Class, Amount: amounts[i]}
+		if total.IsPositive() {
+			trade.Share = class.Value.Add(amounts[i]).Div(total).InexactFloat64()
+		}
+		trades = append(trades, trade)
+	}
+	return trades
+}
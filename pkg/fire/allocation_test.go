@@ -0,0 +1,99 @@
+package fire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const allocationJournal = `fire:target us-equity 60%, bonds 40%
+
+commodity VTI
+    ; class: us-equity
+commodity BND
+    class: bonds
+
+2024/01/01 Deposit
+	assets:bank  $2,000.00
+	equity:opening
+
+2024/01/02 Buy
+	assets:brokerage   10 VTI @ $100.00
+	equity:trading    -10 VTI
+	assets:brokerage   10 BND @ $50.00
+	equity:trading    -10 BND
+	equity:trading     $1,500.00
+	assets:bank       -$1,500.00
+
+P 2024/06/30 VTI $120.00
+P 2024/06/30 BND $48.00
+`
+
+func TestComputeAllocation(t *testing.T) {
+	journal, txs := parseJournal(t, allocationJournal)
+	allocation, err := ComputeAllocation(journal, txs, journal.DefaultCurrency, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !allocation.Total.Equal(decimal.New(2180, 0)) || len(allocation.Warnings) != 0 {
+		t.Fatalf("expected a total of 2180 without warnings, got %+v", allocation)
+	}
+	expected := []struct {
+		class  string
+		value  int64
+		target float64
+	}{{"us-equity", 1200, 0.6}, {"bonds", 480, 0.4}, {"cash", 500, 0}}
+	if len(allocation.Classes) != len(expected) {
+		t.Fatalf("expected %d classes, got %+v", len(expected), allocation.Classes)
+	}
+	for i, e := range expected {
+		class := allocation.Classes[i]
+		if class.Class != e.class || !class.Value.Equal(decimal.New(e.value, 0)) || class.Target != e.target {
+			t.Errorf("expected %s of %d, got %+v", e.class, e.value, class)
+		}
+	}
+
+	// at cost before the prices
+	allocation, _ = ComputeAllocation(journal, txs, journal.DefaultCurrency, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	if !allocation.Total.Equal(decimal.New(2000, 0)) {
+		t.Errorf("expected a total of 2000, got %s", allocation.Total)
+	}
+
+	journal, txs = parseJournal(t, "2024/01/01 Gift\n\tassets:safe  1 XAU\n\tequity:opening\n")
+	allocation, _ = ComputeAllocation(journal, txs, journal.DefaultCurrency, time.Time{})
+	if len(allocation.Warnings) != 1 || allocation.Targeted {
+		t.Errorf("expected a missing price without a target, got %+v", allocation)
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	journal, txs := parseJournal(t, allocationJournal)
+	allocation, err := ComputeAllocation(journal, txs, journal.DefaultCurrency, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(trades []Trade, expected map[string]string) {
+		t.Helper()
+		if len(trades) != len(expected) {
+			t.Fatalf("expected %d trades, got %+v", len(expected), trades)
+		}
+		for _, trade := range trades {
+			if trade.Amount.String() != expected[trade.Class] {
+				t.Errorf("expected %s of %s, got %s", expected[trade.Class], trade.Class, trade.Amount)
+			}
+		}
+	}
+
+	// 3000 after the contribution, the cash has no target
+	contribution := decimal.New(820, 0)
+	check(Rebalance(allocation, contribution, true),
+		map[string]string{"us-equity": "600", "bonds": "720", "cash": "-500"})
+	// the gaps are 600 and 720
+	check(Rebalance(allocation, contribution, false),
+		map[string]string{"us-equity": "372.73", "bonds": "447.27"})
+	check(Rebalance(allocation, decimal.Zero, false), map[string]string{})
+
+	allocation.Targeted = false
+	check(Rebalance(allocation, contribution, true), map[string]string{})
+}
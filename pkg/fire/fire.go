@@ -0,0 +1,258 @@
+// Package fire computes the figures of financial independence
+// planning from a journal: savings rate, FI number and the time
+// left to reach it
+package fire
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the settings read from the journal, with their defaults
+const (
+	WithdrawalRateSetting = "fire:withdrawal-rate" // 4%
+	RealReturnSetting     = "fire:real-return"     // 5%, after inflation
+	CurrencySetting       = "fire:currency"        // the journal currency
+	HorizonSetting        = "fire:horizon"         // 30 years of retirement
+	InvestmentsSetting    = "fire:investments"     // the accounts of the returns, a regular expression
+)
+
+// the investment accounts, without a fire:investments setting
+const DefaultInvestments = "^assets:(investments|brokerage|retirement)"
+
+// InvestmentsPattern returns the pattern of the investment accounts
+func InvestmentsPattern(journal pta.Journal) string {
+	if value, found := journal.Setting(InvestmentsSetting); found {
+		return value
+	}
+	return DefaultInvestments
+}
+
+type Config struct {
+	WithdrawalRate float64
+	RealReturn     float64
+	Currency       pta.Commodity
+	Horizon        int // years of retirement
+}
+
+// ConfigFromJournal reads the settings of the journal:
+//
+//	fire:withdrawal-rate 3.5%
+//	fire:real-return 4%
+//	fire:currency CAD
+//	fire:horizon 40
+func ConfigFromJournal(journal pta.Journal) (Config, error) {
+	config := Config{
+		WithdrawalRate: 0.04,
+		RealReturn:     0.05,
+		Currency:       journal.DefaultCurrency,
+		Horizon:        30,
+	}
+	if value, found := journal.Setting(WithdrawalRateSetting); found {
+		rate, err := ParseRate(value)
+		if err != nil || rate <= 0 {
+			return config, fmt.Errorf("%s must be a positive rate, got '%s'", WithdrawalRateSetting, value)
+		}
+		config.WithdrawalRate = rate
+	}
+	if value, found := journal.Setting(RealReturnSetting); found {
+		rate, err := ParseRate(value)
+		if err != nil {
+			return config, fmt.Errorf("%s must be a rate, got '%s'", RealReturnSetting, value)
+		}
+		config.RealReturn = rate
+	}
+	if value, found := journal.Setting(CurrencySetting); found {
+		config.Currency = pta.CommodityFromCode(value)
+	}
+	if value, found := journal.Setting(HorizonSetting); found {
+		years, err := strconv.Atoi(value)
+		if err != nil || years <= 0 {
+			return config, fmt.Errorf("%s must be a number of years, got '%s'", HorizonSetting, value)
+		}
+		config.Horizon = years
+	}
+	return config, nil
+}
+
+// ParseRate reads a percentage '3.5%' or a fraction '0.035'
+func ParseRate(value string) (float64, error) {
+	percent := strings.HasSuffix(value, "%")
+	rate, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+	if err != nil {
+		return 0, err
+	}
+	if percent {
+		rate /= 100
+	}
+	return rate, nil
+}
+
+// Month is the income statement of a month, in the currency of
+// the config
+type Month struct {
+	Start       time.Time
+	Income      decimal.Decimal
+	Expenses    decimal.Decimal
+	Savings     decimal.Decimal
+	SavingsRate float64 // of the income, zero without income
+}
+
+type Report struct {
+	Config
+	End    time.Time
+	Months []Month
+
+	// the trailing 12 months, annualized with less history
+	Income      decimal.Decimal
+	Expenses    decimal.Decimal
+	Savings     decimal.Decimal
+	SavingsRate float64
+
+	FINumber  decimal.Decimal // the expenses over the withdrawal rate
+	Portfolio decimal.Decimal // the net worth, at market value
+	Progress  float64         // of the FI number
+	YearsToFI float64         // -1 when the savings never get there
+
+	Warnings []string
+}
+
+// Plan computes the report with the transactions up to the end
+// date (included), a zero end date uses the last transaction
+func Plan(journal pta.Journal, txs []pta.Transaction, config Config, end time.Time) Report {
+	if end.IsZero() {
+		for _, tx := range txs {
+			if tx.Date.After(end) {
+				end = tx.Date
+			}
+		}
+	}
+	report := Report{Config: config, End: end}
+	var until []pta.Transaction
+	for _, tx := range txs {
+		if !tx.Date.After(end) {
+			until = append(until, tx)
+		}
+	}
+	if len(until) == 0 {
+		report.YearsToFI = -1
+		return report
+	}
+	prices := pta.NewPriceHistory(journal.AllPrices())
+
+	report.Months = Months(until, prices, config.Currency, &report.Warnings)
+	trailing := report.Months[max(0, len(report.Months)-12):]
+	for _, month := range trailing {
+		report.Income = report.Income.Add(month.Income)
+		report.Expenses = report.Expenses.Add(month.Expenses)
+	}
+	if len(trailing) < 12 {
+		scale := decimal.NewFromInt(12).Div(decimal.NewFromInt(int64(len(trailing))))
+		report.Income = report.Income.Mul(scale).Round(2)
+		report.Expenses = report.Expenses.Mul(scale).Round(2)
+		report.Warnings = append(report.Warnings,
+			fmt.Sprintf("only %d months of history, the yearly figures are annualized", len(trailing)))
+	}
+	report.Savings = report.Income.Sub(report.Expenses)
+	report.SavingsRate = savingsRate(report.Income, report.Savings)
+	report.FINumber = report.Expenses.Div(decimal.NewFromFloat(config.WithdrawalRate)).Round(2)
+
+	v := pta.Valuation{Mode: pta.ValueAtEnd, Target: config.Currency, End: end, Prices: prices}
+	balance := v.BalanceStatement(pta.OpeningBalance(until))
+	report.Portfolio = balance.Total
+	report.Warnings = append(report.Warnings, balance.Warnings...)
+
+	if report.FINumber.IsPositive() {
+		report.Progress = report.Portfolio.Div(report.FINumber).InexactFloat64()
+	}
+	report.YearsToFI = YearsToFI(report.Portfolio.InexactFloat64(), report.Savings.InexactFloat64(),
+		report.FINumber.InexactFloat64(), config.RealReturn)
+	return report
+}
+
+// Months returns the income statement of each month, from the
+// first transaction to the last. The amounts in other currencies
+// are converted at the end of the month, the ones without a price
+// are left out with a warning
+func Months(txs []pta.Transaction, prices pta.PriceHistory, currency pta.Commodity, warnings *[]string) []Month {
+	byMonth := make(map[time.Time][]pta.Transaction)
+	var first, last time.Time
+	for _, tx := range txs {
+		start := pta.Monthly.Start(tx.Date)
+		byMonth[start] = append(byMonth[start], tx)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+
+	var months []Month
+	for start := first; !start.After(last); start = pta.Monthly.Next(start) {
+		month := Month{Start: start}
+		end := pta.Monthly.End(start)
+		revenue, expenses := pta.ComputeIncomeStatement(byMonth[start]).Totals()
+		month.Income = convertLots(revenue, prices, currency, end, warnings)
+		month.Expenses = convertLots(expenses, prices, currency, end, warnings)
+		month.Savings = month.Income.Sub(month.Expenses)
+		month.SavingsRate = savingsRate(month.Income, month.Savings)
+		months = append(months, month)
+	}
+	return months
+}
+
+func convertLots(lots []pta.Lot, prices pta.PriceHistory, currency pta.Commodity, date time.Time, warnings *[]string) decimal.Decimal {
+	var total decimal.Decimal
+	for _, lot := range lots {
+		value, _, found := prices.Convert(lot.Amount, lot.Commodity.Code, currency.Code, date)
+		if !found {
+			warning := fmt.Sprintf("no price for %s in %s, left out of the income", lot.Commodity.Code, currency.Code)
+			if !slices.Contains(*warnings, warning) {
+				*warnings = append(*warnings, warning)
+			}
+			continue
+		}
+		total = total.Add(value)
+	}
+	return total
+}
+
+func savingsRate(income, savings decimal.Decimal) float64 {
+	if !income.IsPositive() {
+		return 0
+	}
+	return savings.Div(income).InexactFloat64()
+}
+
+// YearsToFI is the time for the portfolio, growing at the real
+// return with the yearly savings, to reach the FI number, -1 when
+// it never does
+func YearsToFI(portfolio, savings, fiNumber, realReturn float64) float64 {
+	if portfolio >= fiNumber {
+		return 0
+	}
+	if realReturn == 0 {
+		if savings <= 0 {
+			return -1
+		}
+		return (fiNumber - portfolio) / savings
+	}
+	// portfolio (1+r)^n + savings ((1+r)^n - 1) / r = fiNumber
+	growth := (fiNumber*realReturn + savings) / (portfolio*realReturn + savings)
+	if portfolio*realReturn+savings <= 0 || growth <= 0 {
+		return -1
+	}
+	years := math.Log(growth) / math.Log(1+realReturn)
+	if math.IsNaN(years) || math.IsInf(years, 0) || years < 0 {
+		return -1
+	}
+	return years
+}
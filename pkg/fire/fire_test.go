@@ -0,0 +1,112 @@
+package fire
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// a year of 5000 income and 3000 expenses a month
+func testJournal(settings string) string {
+	var sb strings.Builder
+	sb.WriteString(settings + "\n")
+	sb.WriteString("2024/01/01 Opening\n\tassets:brokerage  $10,000.00\n\tequity:opening\n\n")
+	for month := 1; month <= 12; month++ {
+		fmt.Fprintf(&sb, "2024/%02d/01 Payroll\n\tassets:bank  $5,000.00\n\tincome:salary\n\n", month)
+		fmt.Fprintf(&sb, "2024/%02d/15 Rent\n\texpenses:rent  $3,000.00\n\tassets:bank\n\n", month)
+	}
+	return sb.String()
+}
+
+func parseJournal(t *testing.T, in string) (pta.Journal, []pta.Transaction) {
+	d := pta.NewDecoder(strings.NewReader(in))
+	var txs []pta.Transaction
+	for tx, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	return *d.Journal(), txs
+}
+
+func TestPlan(t *testing.T) {
+	journal, txs := parseJournal(t, testJournal("fire:withdrawal-rate 3.5%\nfire:real-return 5%\n"))
+	config, err := ConfigFromJournal(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.WithdrawalRate != 0.035 || config.RealReturn != 0.05 || config.Currency.Code != "USD" {
+		t.Fatalf("unexpected config %+v", config)
+	}
+
+	report := Plan(journal, txs, config, time.Time{})
+	if len(report.Months) != 12 || report.Months[0].SavingsRate != 0.4 {
+		t.Fatalf("expected 12 months saving 40%%, got %+v", report.Months)
+	}
+	if !report.Expenses.Equal(decimal.New(36000, 0)) || !report.Savings.Equal(decimal.New(24000, 0)) ||
+		report.SavingsRate != 0.4 {
+		t.Errorf("unexpected trailing year %s %s %v", report.Expenses, report.Savings, report.SavingsRate)
+	}
+	if !report.FINumber.Equal(decimal.RequireFromString("1028571.43")) {
+		t.Errorf("expected a FI number of 1028571.43, got %s", report.FINumber)
+	}
+	if !report.Portfolio.Equal(decimal.New(34000, 0)) {
+		t.Errorf("expected a portfolio of 34000, got %s", report.Portfolio)
+	}
+
+	// a year before FI the portfolio is short, a year after it is not
+	years := report.YearsToFI
+	if years < 1 || len(report.Warnings) != 0 {
+		t.Fatalf("unexpected years to FI %v %v", years, report.Warnings)
+	}
+	grow := func(n int) float64 {
+		p := 34000.0
+		for range n {
+			p = p*1.05 + 24000
+		}
+		return p
+	}
+	if grow(int(years)) >= 1028571.43 || grow(int(years)+1) < 1028571.43 {
+		t.Errorf("years to FI %v does not match the yearly growth", years)
+	}
+
+	// half a year of history is annualized
+	report = Plan(journal, txs, config, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC))
+	if len(report.Months) != 6 || !report.Expenses.Equal(decimal.New(36000, 0)) || len(report.Warnings) != 1 {
+		t.Errorf("expected the annualized expenses and a warning, got %s %v", report.Expenses, report.Warnings)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, setting := range []string{"fire:withdrawal-rate 0%", "fire:withdrawal-rate four", "fire:real-return 5 percent"} {
+		journal, _ := parseJournal(t, setting+"\n")
+		if _, err := ConfigFromJournal(journal); err == nil {
+			t.Errorf("expected an error for '%s'", setting)
+		}
+	}
+}
+
+func TestYearsToFI(t *testing.T) {
+	cases := []struct {
+		portfolio, savings, fiNumber, realReturn float64
+		expected                                 float64
+	}{
+		{100, 0, 100, 0.05, 0},
+		{0, 10, 100, 0, 10},
+		{0, 0, 100, 0, -1},
+		{50, -10, 100, 0.05, -1},
+		{50, 0, 100, 0.05, math.Log(2) / math.Log(1.05)},
+	}
+	for _, c := range cases {
+		got := YearsToFI(c.portfolio, c.savings, c.fiNumber, c.realReturn)
+		if math.Abs(got-c.expected) > 1e-9 {
+			t.Errorf("%+v: expected %v, got %v", c, c.expected, got)
+		}
+	}
+}
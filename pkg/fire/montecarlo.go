@@ -0,0 +1,383 @@
+package fire
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The Monte Carlo simulation draws yearly returns and inflation,
+// either from a parametric model (normal returns, independent
+// between the asset classes) or by bootstrapping the years of a
+// historical returns CSV:
+//
+//	year,inflation,stocks,bonds
+//	1990,6.1%,-3.1%,9.0%
+//
+// The US returns of stocks, bonds and cash since 1928 ship with
+// fireside (history.csv) and are bootstrapped by default, unless
+// the journal sets its own returns. Another CSV can be provided
+//
+// The portfolio and the withdrawals are in today's money, the
+// returns are deflated by the inflation of the same year
+
+// the settings of the model, with their defaults
+const (
+	AllocationSetting = "fire:allocation" // stocks 100%
+	ReturnSetting     = "fire:return:"    // fire:return:stocks 7% 17%, mean and volatility
+	InflationSetting  = "fire:inflation"  // 2.5% 1%, mean and volatility
+	HistorySetting    = "fire:history"    // a CSV to bootstrap, relative to the journal
+)
+
+//go:embed history.csv
+var bundledHistory string
+
+// the default parametric model, round figures to be replaced
+// with one's own assumptions, they are not fitted to any data
+var defaultReturns = map[string]AssetClass{
+	"stocks": {Name: "stocks", Return: 0.07, Volatility: 0.17},
+	"bonds":  {Name: "bonds", Return: 0.03, Volatility: 0.06},
+	"cash":   {Name: "cash", Return: 0.02, Volatility: 0.01},
+}
+
+type AssetClass struct {
+	Name       string
+	Weight     float64 // of the portfolio, rebalanced every year
+	Return     float64 // yearly mean, before inflation
+	Volatility float64 // standard deviation of the yearly return
+}
+
+type Model struct {
+	Classes             []AssetClass
+	Inflation           float64
+	InflationVolatility float64
+	History             []HistoricalYear // bootstrapped when set
+}
+
+type HistoricalYear struct {
+	Year      int
+	Inflation float64
+	Returns   map[string]float64 // by asset class
+}
+
+type Strategy int
+
+const (
+	FixedWithdrawal   Strategy = iota // the first withdrawal, kept in today's money
+	PercentWithdrawal                 // a share of the portfolio each year
+	Guardrails                        // fixed, cut or raised by 10% when the rate drifts 20% away
+)
+
+func ParseStrategy(strategy string) (Strategy, error) {
+	switch strategy {
+	case "fixed":
+		return FixedWithdrawal, nil
+	case "percentage":
+		return PercentWithdrawal, nil
+	case "guardrails":
+		return Guardrails, nil
+	}
+	return 0, fmt.Errorf("unknown strategy '%s' (fixed, percentage or guardrails)", strategy)
+}
+
+type Simulation struct {
+	Model
+	Strategy
+	Portfolio     float64
+	Contribution  float64 // yearly, until retirement
+	YearsToRetire int
+	Years         int     // of retirement
+	Withdrawal    float64 // yearly, for fixed and guardrails
+	Rate          float64 // of the portfolio, for percentage
+	Runs          int
+	Seed          uint64
+}
+
+// Band holds the percentiles of the runs for a year from now
+type Band struct {
+	Year                    int
+	P10, P25, P50, P75, P90 float64
+}
+
+type SimulationResult struct {
+	Runs      int
+	Success   float64 // share of the runs never running out of money
+	Portfolio []Band  // at the end of each year
+	Spending  []Band  // the withdrawals of each year of retirement
+}
+
+// ModelFromJournal reads the allocation and the assumptions of
+// the journal settings:
+//
+//	fire:allocation stocks 80%, bonds 20%
+//	fire:return:stocks 6.5% 16%
+//	fire:inflation 2.5% 1%
+//
+// Without returns nor inflation, the bundled history is bootstrapped
+func ModelFromJournal(journal pta.Journal) (Model, error) {
+	model := Model{Inflation: 0.025, InflationVolatility: 0.01}
+	allocation := "stocks 100%"
+	if value, found := journal.Setting(AllocationSetting); found {
+		allocation = value
+	}
+	weights, err := parseWeights(AllocationSetting, allocation)
+	if err != nil {
+		return model, err
+	}
+	custom := false // returns or inflation set in the journal
+	for _, weight := range weights {
+		class, found := defaultReturns[weight.Name]
+		class.Name, class.Weight = weight.Name, weight.Weight
+		if value, ok := journal.Setting(ReturnSetting + class.Name); ok {
+			custom = true
+			var err error
+			class.Return, class.Volatility, err = parseMeanVolatility(value)
+			if err != nil {
+				return model, fmt.Errorf("%s%s: %s", ReturnSetting, class.Name, err)
+			}
+		} else if !found {
+			return model, fmt.Errorf("no returns for '%s', set %s%s", class.Name, ReturnSetting, class.Name)
+		}
+		model.Classes = append(model.Classes, class)
+	}
+	if value, found := journal.Setting(InflationSetting); found {
+		var err error
+		model.Inflation, model.InflationVolatility, err = parseMeanVolatility(value)
+		if err != nil {
+			return model, fmt.Errorf("%s: %s", InflationSetting, err)
+		}
+		custom = true
+	}
+
+	// the default classes are the ones of the bundled history
+	if !custom {
+		model.History, err = BundledHistory()
+	}
+	return model, err
+}
+
+// BundledHistory returns the yearly US returns shipped with
+// fireside, from 1928
+func BundledHistory() ([]HistoricalYear, error) {
+	return LoadHistory(strings.NewReader(bundledHistory))
+}
+
+// SimulationFromPlan starts from the journal figures: the net
+// worth, saving the yearly savings until FI, then withdrawing the
+// yearly expenses
+func SimulationFromPlan(report Report, model Model) Simulation {
+	sim := Simulation{
+		Model:        model,
+		Portfolio:    report.Portfolio.InexactFloat64(),
+		Contribution: max(0, report.Savings.InexactFloat64()),
+		Years:        report.Horizon,
+		Withdrawal:   report.Expenses.InexactFloat64(),
+		Rate:         report.WithdrawalRate,
+		Runs:         10000,
+		Seed:         1,
+	}
+	if report.YearsToFI > 0 {
+		sim.YearsToRetire = int(math.Ceil(report.YearsToFI))
+	}
+	return sim
+}
+
+// the weights of 'class weight, ...', adding up to 100%
+func parseWeights(setting, value string) ([]AssetClass, error) {
+	var classes []AssetClass
+	var total float64
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s expects 'class weight, ...', got '%s'", setting, value)
+		}
+		weight, err := ParseRate(fields[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("%s: bad weight '%s'", setting, fields[1])
+		}
+		total += weight
+		classes = append(classes, AssetClass{Name: fields[0], Weight: weight})
+	}
+	if math.Abs(total-1) > 0.001 {
+		return nil, fmt.Errorf("%s must add up to 100%%, got %.1f%%", setting, total*100)
+	}
+	return classes, nil
+}
+
+func parseMeanVolatility(value string) (mean, volatility float64, err error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("expected a mean and a volatility, got '%s'", value)
+	}
+	mean, err = ParseRate(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad mean '%s'", fields[0])
+	}
+	volatility, err = ParseRate(fields[1])
+	if err != nil || volatility < 0 {
+		return 0, 0, fmt.Errorf("bad volatility '%s'", fields[1])
+	}
+	return mean, volatility, nil
+}
+
+// LoadHistory reads the yearly returns to bootstrap, with a
+// header 'year,inflation,<class>,...' and rates like '7.1%' or 0.071.
+// The lines starting with '#' are comments
+func LoadHistory(r io.Reader) ([]HistoricalYear, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("history: expected a header and at least a year")
+	}
+	header := records[0]
+	if len(header) < 3 || header[0] != "year" || header[1] != "inflation" {
+		return nil, fmt.Errorf("history: the header must be 'year,inflation,<class>,...', got '%s'", strings.Join(header, ","))
+	}
+	history := make([]HistoricalYear, 0, len(records)-1)
+	for i, record := range records[1:] {
+		year := HistoricalYear{Returns: make(map[string]float64)}
+		year.Year, err = strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("history:%d: bad year '%s'", i+2, record[0])
+		}
+		year.Inflation, err = ParseRate(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("history:%d: bad inflation '%s'", i+2, record[1])
+		}
+		for j, class := range header[2:] {
+			year.Returns[class], err = ParseRate(record[j+2])
+			if err != nil {
+				return nil, fmt.Errorf("history:%d: bad return of %s '%s'", i+2, class, record[j+2])
+			}
+		}
+		history = append(history, year)
+	}
+	return history, nil
+}
+
+// Simulate runs the simulation, the same seed gives the same
+// result
+func Simulate(sim Simulation) (SimulationResult, error) {
+	if sim.Runs <= 0 {
+		return SimulationResult{}, fmt.Errorf("the number of runs must be positive")
+	}
+	if sim.Years <= 0 || sim.YearsToRetire < 0 {
+		return SimulationResult{}, fmt.Errorf("the number of years must be positive")
+	}
+	for _, class := range sim.Classes {
+		if len(sim.History) > 0 {
+			if _, found := sim.History[0].Returns[class.Name]; !found {
+				return SimulationResult{}, fmt.Errorf("no '%s' returns in the history", class.Name)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(sim.Seed, sim.Seed^0x9e3779b97f4a7c15))
+	years := sim.YearsToRetire + sim.Years
+	portfolios := make([][]float64, years) // by year, then run
+	spending := make([][]float64, sim.Years)
+	for y := range portfolios {
+		portfolios[y] = make([]float64, sim.Runs)
+	}
+	for y := range spending {
+		spending[y] = make([]float64, sim.Runs)
+	}
+
+	success := 0
+	for run := range sim.Runs {
+		portfolio := sim.Portfolio
+		withdrawal := sim.Withdrawal
+		var initialRate float64
+		failed := false
+		for y := range years {
+			growth := sim.growth(rng)
+			if y < sim.YearsToRetire {
+				portfolio = portfolio*growth + sim.Contribution
+				portfolios[y][run] = portfolio
+				continue
+			}
+
+			// withdrawn at the start of the year
+			switch sim.Strategy {
+			case PercentWithdrawal:
+				withdrawal = portfolio * sim.Rate
+			case Guardrails:
+				switch {
+				case portfolio <= 0:
+					// nothing to withdraw from, the run fails below
+				case y == sim.YearsToRetire:
+					initialRate = withdrawal / portfolio
+				default:
+					rate := withdrawal / portfolio
+					if rate > initialRate*1.2 {
+						withdrawal *= 0.9
+					} else if rate < initialRate*0.8 {
+						withdrawal *= 1.1
+					}
+				}
+			}
+			// nothing is spent from a negative net worth
+			spent := max(0, min(withdrawal, portfolio))
+			if withdrawal > portfolio || portfolio <= 0 {
+				failed = true
+			}
+			portfolio = (portfolio - spent) * growth
+			portfolios[y][run] = portfolio
+			spending[y-sim.YearsToRetire][run] = spent
+		}
+		if !failed {
+			success++
+		}
+	}
+
+	result := SimulationResult{
+		Runs:    sim.Runs,
+		Success: float64(success) / float64(sim.Runs),
+	}
+	for y, values := range portfolios {
+		result.Portfolio = append(result.Portfolio, percentiles(y+1, values))
+	}
+	for y, values := range spending {
+		result.Spending = append(result.Spending, percentiles(sim.YearsToRetire+y+1, values))
+	}
+	return result, nil
+}
+
+// the real growth of the portfolio over a year
+func (m Model) growth(rng *rand.Rand) float64 {
+	var nominal, inflation float64
+	if len(m.History) > 0 {
+		year := m.History[rng.IntN(len(m.History))]
+		for _, class := range m.Classes {
+			nominal += class.Weight * year.Returns[class.Name]
+		}
+		inflation = year.Inflation
+	} else {
+		for _, class := range m.Classes {
+			nominal += class.Weight * (class.Return + class.Volatility*rng.NormFloat64())
+		}
+		inflation = m.Inflation + m.InflationVolatility*rng.NormFloat64()
+	}
+	// can't lose more than everything
+	return max(0, 1+nominal) / (1 + inflation)
+}
+
+func percentiles(year int, values []float64) Band {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	at := func(p float64) float64 {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return Band{Year: year, P10: at(0.10), P25: at(0.25), P50: at(0.50), P75: at(0.75), P90: at(0.90)}
+}
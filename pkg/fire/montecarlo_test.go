@@ -0,0 +1,225 @@
+package fire
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// no volatility, the outcome is known
+var flatModel = Model{Classes: []AssetClass{{Name: "cash", Weight: 1}}}
+
+func TestSimulateFlat(t *testing.T) {
+	cases := []struct {
+		withdrawal float64
+		success    float64
+	}{
+		{40, 1}, // 25 years of withdrawals
+		{50, 0}, // 20 years
+	}
+	for _, c := range cases {
+		result, err := Simulate(Simulation{
+			Model:      flatModel,
+			Portfolio:  1000,
+			Years:      25,
+			Withdrawal: c.withdrawal,
+			Runs:       10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Success != c.success {
+			t.Errorf("withdrawing %v: expected a success of %v, got %v", c.withdrawal, c.success, result.Success)
+		}
+	}
+
+	// saving first, 3 years of 100
+	result, err := Simulate(Simulation{
+		Model:         flatModel,
+		Portfolio:     700,
+		Contribution:  100,
+		YearsToRetire: 3,
+		Years:         25,
+		Withdrawal:    40,
+		Runs:          1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success != 1 || result.Portfolio[2].P50 != 1000 || result.Portfolio[3].P50 != 960 {
+		t.Errorf("unexpected result %v %+v", result.Success, result.Portfolio[:4])
+	}
+	if len(result.Spending) != 25 || result.Spending[0].Year != 4 || result.Spending[0].P50 != 40 {
+		t.Errorf("unexpected spending %+v", result.Spending[0])
+	}
+}
+
+func TestSimulateStrategies(t *testing.T) {
+	model := Model{
+		Classes:             []AssetClass{{Name: "stocks", Weight: 1, Return: 0.07, Volatility: 0.17}},
+		Inflation:           0.025,
+		InflationVolatility: 0.01,
+	}
+	sim := Simulation{Model: model, Portfolio: 1000, Years: 30, Withdrawal: 60, Rate: 0.06, Runs: 2000, Seed: 42}
+
+	fixed, err := Simulate(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Simulate(sim)
+	if !reflect.DeepEqual(fixed, again) {
+		t.Error("expected the same result with the same seed")
+	}
+	if fixed.Success <= 0 || fixed.Success >= 1 {
+		t.Errorf("expected some runs to fail at 6%%, got a success of %v", fixed.Success)
+	}
+	for _, band := range fixed.Portfolio {
+		if band.P10 > band.P25 || band.P25 > band.P50 || band.P50 > band.P75 || band.P75 > band.P90 {
+			t.Fatalf("percentiles out of order %+v", band)
+		}
+	}
+
+	// never runs out, the spending varies instead
+	sim.Strategy = PercentWithdrawal
+	percent, _ := Simulate(sim)
+	if percent.Success != 1 || percent.Spending[29].P10 >= percent.Spending[29].P90 {
+		t.Errorf("unexpected percentage result %v %+v", percent.Success, percent.Spending[29])
+	}
+
+	// cutting the spending in bad years helps
+	sim.Strategy = Guardrails
+	guardrails, _ := Simulate(sim)
+	if guardrails.Success <= fixed.Success {
+		t.Errorf("expected the guardrails to do better than %v, got %v", fixed.Success, guardrails.Success)
+	}
+}
+
+func TestSimulateGuardrailsEmptyPortfolio(t *testing.T) {
+	for _, portfolio := range []float64{0, -500} {
+		result, err := Simulate(Simulation{
+			Model:      flatModel,
+			Strategy:   Guardrails,
+			Portfolio:  portfolio,
+			Years:      5,
+			Withdrawal: 40,
+			Runs:       10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Success != 0 {
+			t.Errorf("portfolio of %v: expected every run to fail, got %v", portfolio, result.Success)
+		}
+		for _, band := range append(result.Portfolio, result.Spending...) {
+			for _, v := range []float64{band.P10, band.P25, band.P50, band.P75, band.P90} {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					t.Fatalf("portfolio of %v: expected finite percentiles, got %+v", portfolio, band)
+				}
+			}
+		}
+		for _, band := range result.Spending {
+			if band.P10 < 0 {
+				t.Errorf("portfolio of %v: expected no negative spending, got %+v", portfolio, band)
+			}
+		}
+	}
+}
+
+func TestSimulateHistory(t *testing.T) {
+	history, err := LoadHistory(strings.NewReader("year,inflation,stocks,bonds\n" +
+		"2001,2%,10%,4%\n" +
+		"2002,2%,-20%,6%\n" +
+		"2003,0.02,0.3,0.02\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[1].Returns["stocks"] != -0.2 || history[2].Inflation != 0.02 {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	model := Model{Classes: []AssetClass{{Name: "stocks", Weight: 0.5}, {Name: "bonds", Weight: 0.5}}, History: history}
+	result, err := Simulate(Simulation{Model: model, Portfolio: 1000, Years: 10, Withdrawal: 40, Runs: 500, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// even ten of the worst year in a row don't run out
+	if result.Success != 1 {
+		t.Errorf("expected every run to succeed, got %v", result.Success)
+	}
+
+	model.Classes = []AssetClass{{Name: "gold", Weight: 1}}
+	if _, err := Simulate(Simulation{Model: model, Portfolio: 1000, Years: 10, Runs: 1}); err == nil {
+		t.Error("expected an error for a class missing from the history")
+	}
+	if _, err := LoadHistory(strings.NewReader("year,stocks\n2001,10%\n")); err == nil {
+		t.Error("expected an error without inflation")
+	}
+}
+
+func TestBundledHistory(t *testing.T) {
+	history, err := BundledHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 96 || history[0].Year != 1928 || history[len(history)-1].Year != 2023 {
+		t.Fatalf("expected the years 1928 to 2023, got %d years", len(history))
+	}
+	for i, year := range history {
+		if year.Year != 1928+i || len(year.Returns) != 3 {
+			t.Fatalf("unexpected year %+v", year)
+		}
+	}
+	if history[80].Returns["stocks"] != -0.3655 {
+		t.Errorf("expected the 2008 stocks return of -36.55%%, got %v", history[80].Returns["stocks"])
+	}
+
+	// the default model
+	journal, _ := parseJournal(t, "fire:allocation stocks 60%, bonds 30%, cash 10%\n")
+	model, err := ModelFromJournal(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(model.History, history) {
+		t.Errorf("expected the bundled history by default")
+	}
+	result, err := Simulate(Simulation{Model: model, Portfolio: 1000, Years: 30, Withdrawal: 40, Runs: 1000, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success <= 0.5 || result.Success >= 1 {
+		t.Errorf("expected most runs to succeed at 4%%, got %v", result.Success)
+	}
+}
+
+func TestModelFromJournal(t *testing.T) {
+	journal, _ := parseJournal(t, "fire:allocation stocks 80%, bonds 20%\n"+
+		"fire:return:stocks 6% 15%\n"+
+		"fire:inflation 3% 1.5%\n")
+	model, err := ModelFromJournal(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Model{
+		Classes: []AssetClass{
+			{Name: "stocks", Weight: 0.8, Return: 0.06, Volatility: 0.15},
+			{Name: "bonds", Weight: 0.2, Return: 0.03, Volatility: 0.06},
+		},
+		Inflation:           0.03,
+		InflationVolatility: 0.015,
+	}
+	if !reflect.DeepEqual(model, expected) {
+		t.Errorf("expected %+v, got %+v", expected, model)
+	}
+
+	for _, settings := range []string{
+		"fire:allocation stocks 80%",
+		"fire:allocation gold 100%",
+		"fire:allocation stocks 100%\nfire:return:stocks 7%",
+	} {
+		journal, _ := parseJournal(t, settings+"\n")
+		if _, err := ModelFromJournal(journal); err == nil {
+			t.Errorf("expected an error for '%s'", settings)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// suggestions need more than this confidence to be applied to
+// the imported transactions, a single payee observation is 0.5
+var MinConfidence = 0.5
+
+// Categorizer suggests the expense (or income) account of a
+// transaction from its description. It learns from the journal:
+// the accounts used with each payee, and the accounts associated
+// with each word of the descriptions (naive Bayes). Explicit
+// rules take precedence over what was learned
+type Categorizer struct {
+	rules []CategoryRule
+
+	// normalizePayee -> account -> count, the same payee as the
+	// duplicates detection
+	payees map[string]map[string]int
+
+	// naive Bayes over the description words
+	accounts   map[string]int            // transactions per account
+	words      map[string]map[string]int // account -> word -> count
+	wordTotals map[string]int            // words per account
+	vocabulary map[string]bool
+	total      int
+}
+
+type CategoryRule struct {
+	Pattern *regexp.Regexp
+	Account string
+}
+
+type Suggestion struct {
+	Account    string
+	Confidence float64 // from 0 to 1
+	Source     string  // rule, payee or words
+}
+
+// Confident reports whether the suggestion can be applied
+func (s Suggestion) Confident() bool {
+	return s.Confidence > MinConfidence
+}
+
+func (s Suggestion) String() string {
+	return fmt.Sprintf("%s (%.0f%% from %s)", s.Account, s.Confidence*100, s.Source)
+}
+
+// NewCategorizer learns from the journal transactions. The rules
+// conditions that assign account2 (on the whole entry or the
+// description) are used as explicit rules
+func NewCategorizer(txs []pta.Transaction, rules Rules) *Categorizer {
+	c := &Categorizer{
+		payees:     make(map[string]map[string]int),
+		accounts:   make(map[string]int),
+		words:      make(map[string]map[string]int),
+		wordTotals: make(map[string]int),
+		vocabulary: make(map[string]bool),
+	}
+	for _, cond := range rules.Conditions {
+		account := cond.Assign["account2"]
+		if account == "" || (cond.Field != "" && cond.Field != "description") {
+			continue
+		}
+		for _, re := range cond.Patterns {
+			c.rules = append(c.rules, CategoryRule{Pattern: re, Account: account})
+		}
+	}
+	for _, tx := range txs {
+		c.Learn(tx)
+	}
+	return c
+}
+
+// WithRules returns a copy of the categorizer using other rules,
+// sharing what was learned
+func (c *Categorizer) WithRules(rules Rules) *Categorizer {
+	copy := *c
+	copy.rules = NewCategorizer(nil, rules).rules
+	return &copy
+}
+
+// Learn adds the transaction's category to what the categorizer
+// knows, only transactions with a single expense or income posting
+// are categorized
+func (c *Categorizer) Learn(tx pta.Transaction) {
+	account := category(tx)
+	if account == "" || isUnknownAccount(account) {
+		return
+	}
+	words := descriptionWords(tx.Description)
+	if len(words) == 0 {
+		return
+	}
+
+	payee := normalizePayee(tx.Description)
+	if c.payees[payee] == nil {
+		c.payees[payee] = make(map[string]int)
+	}
+	c.payees[payee][account]++
+
+	if c.words[account] == nil {
+		c.words[account] = make(map[string]int)
+	}
+	for _, word := range words {
+		c.words[account][word]++
+		c.wordTotals[account]++
+		c.vocabulary[word] = true
+	}
+	c.accounts[account]++
+	c.total++
+}
+
+// Suggest returns the most likely account for the description
+func (c *Categorizer) Suggest(description string) (Suggestion, bool) {
+	for _, rule := range c.rules {
+		if rule.Pattern.MatchString(description) {
+			return Suggestion{Account: rule.Account, Confidence: 1, Source: "rule"}, true
+		}
+	}
+
+	words := descriptionWords(description)
+	if len(words) == 0 || c.total == 0 {
+		return Suggestion{}, false
+	}
+
+	if counts, found := c.payees[normalizePayee(description)]; found {
+		best, total := "", 0
+		for account, count := range counts {
+			total += count
+			if count > counts[best] || (count == counts[best] && account < best) {
+				best = account
+			}
+		}
+		// a single observation is not enough to be sure
+		return Suggestion{
+			Account:    best,
+			Confidence: float64(counts[best]) / float64(total+1),
+			Source:     "payee",
+		}, true
+	}
+
+	return c.bayes(words)
+}
+
+// multinomial naive Bayes with Laplace smoothing, the confidence
+// is the posterior probability of the best account
+func (c *Categorizer) bayes(words []string) (Suggestion, bool) {
+	known := 0
+	for _, word := range words {
+		if c.vocabulary[word] {
+			known++
+		}
+	}
+	if known == 0 {
+		return Suggestion{}, false
+	}
+
+	logProbs := make(map[string]float64, len(c.accounts))
+	best := ""
+	vocabulary := float64(len(c.vocabulary))
+	for account, count := range c.accounts {
+		logProb := math.Log(float64(count) / float64(c.total))
+		for _, word := range words {
+			if !c.vocabulary[word] {
+				continue
+			}
+			logProb += math.Log((float64(c.words[account][word]) + 1) /
+				(float64(c.wordTotals[account]) + vocabulary))
+		}
+		logProbs[account] = logProb
+		if best == "" || logProb > logProbs[best] || (logProb == logProbs[best] && account < best) {
+			best = account
+		}
+	}
+
+	// normalized, relative to the best to avoid underflows
+	sum := 0.0
+	for _, logProb := range logProbs {
+		sum += math.Exp(logProb - logProbs[best])
+	}
+	return Suggestion{Account: best, Confidence: 1 / sum, Source: "words"}, true
+}
+
+// Categorize replaces the expenses:unknown and income:unknown
+// postings of the imported transactions with the suggested
+// accounts, when confident enough. The suggestions applied are
+// returned by transaction index
+func (c *Categorizer) Categorize(txs []pta.Transaction) map[int]Suggestion {
+	applied := make(map[int]Suggestion)
+	for i := range txs {
+		tx := &txs[i]
+		for j := range tx.Postings {
+			post := &tx.Postings[j]
+			if !isUnknownAccount(post.Account) {
+				continue
+			}
+			suggestion, found := c.Suggest(tx.Description)
+			if !found || !suggestion.Confident() {
+				continue
+			}
+			// the postings may be shared with the caller's copy
+			postings := make([]pta.Posting, len(tx.Postings))
+			copy(postings, tx.Postings)
+			postings[j].Account = suggestion.Account
+			tx.Postings = postings
+			applied[i] = suggestion
+			break
+		}
+	}
+	return applied
+}
+
+// the counterpart of the transaction: its only expense
+// or income posting
+func category(tx pta.Transaction) (account string) {
+	for _, post := range tx.Postings {
+		if strings.HasPrefix(post.Account, "expenses") || strings.HasPrefix(post.Account, "income") {
+			if account != "" {
+				return ""
+			}
+			account = post.Account
+		}
+	}
+	return account
+}
+
+func isUnknownAccount(account string) bool {
+	return account == "expenses:unknown" || account == "income:unknown"
+}
+
+// the lowercase words of a description, without the numbers,
+// tags, and payment prefixes
+func descriptionWords(desc string) (words []string) {
+	for _, field := range strings.Fields(strings.ToLower(desc)) {
+		if strings.HasPrefix(field, "#") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		}) {
+			if len(word) < 2 || paymentWords[word] {
+				continue
+			}
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+var paymentWords = map[string]bool{
+	"pos": true, "purchase": true, "payment": true, "debit": true, "credit": true,
+	"interac": true, "sq": true, "tst": true, "the": true,
+}
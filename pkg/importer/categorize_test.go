@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func categorizedTx(desc, account string) pta.Transaction {
+	return pta.Transaction{
+		Date:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Description: desc,
+		Postings: []pta.Posting{
+			{Account: "assets:bank", Lot: pta.Lot{Amount: decimal.New(-10, 0), Commodity: pta.DefaultCurrency}},
+			{Account: account, Lot: pta.Lot{Amount: decimal.New(10, 0), Commodity: pta.DefaultCurrency}},
+		},
+	}
+}
+
+func TestCategorizer(t *testing.T) {
+	journal := []pta.Transaction{
+		categorizedTx("STARBUCKS #1234 TORONTO", "expenses:coffee"),
+		categorizedTx("Starbucks Toronto", "expenses:coffee"),
+		categorizedTx("Starbucks Toronto", "expenses:coffee"),
+		categorizedTx("Tim Hortons", "expenses:coffee"),
+		categorizedTx("Metro grocery", "expenses:food:groceries"),
+		categorizedTx("Loblaws grocery", "expenses:food:groceries"),
+		categorizedTx("FreshCo grocery store", "expenses:food:groceries"),
+		categorizedTx("Shell gas station", "expenses:transportation:fuel"),
+		categorizedTx("unknown", "expenses:unknown"),
+	}
+	rules, err := ParseRules(strings.NewReader("if /RENT/ account2 expenses:home:rent\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCategorizer(journal, rules)
+
+	cases := []struct {
+		desc    string
+		account string
+		source  string
+	}{
+		{"POS STARBUCKS #999 TORONTO ON", "expenses:coffee", "payee"},
+		{"Rent January", "expenses:home:rent", "rule"},
+		{"Sobeys grocery", "expenses:food:groceries", "words"},
+		{"Esso gas station", "expenses:transportation:fuel", "words"},
+	}
+	for _, tc := range cases {
+		s, found := c.Suggest(tc.desc)
+		if !found || s.Account != tc.account || s.Source != tc.source {
+			t.Errorf("Suggest(%s) = %v, expected %s from %s", tc.desc, s, tc.account, tc.source)
+		}
+		if s.Confidence <= 0 || s.Confidence > 1 {
+			t.Errorf("Suggest(%s): confidence out of range %f", tc.desc, s.Confidence)
+		}
+	}
+	if s, _ := c.Suggest("Starbucks Toronto"); s.Confidence != 0.75 {
+		t.Errorf("expected 3 of 3 observations to give 0.75, got %f", s.Confidence)
+	}
+	if _, found := c.Suggest("zzz qqq"); found {
+		t.Errorf("expected no suggestion for unknown words")
+	}
+	// the unknown accounts are not learned
+	if _, found := c.Suggest("unknown"); found {
+		t.Errorf("expected no suggestion for the unknown category")
+	}
+
+	imported := []pta.Transaction{
+		categorizedTx("Starbucks Toronto", "expenses:unknown"),
+		categorizedTx("zzz", "expenses:unknown"),
+		categorizedTx("Metro", "expenses:misc"),
+	}
+	original := imported[0].Postings
+	applied := c.Categorize(imported)
+	if len(applied) != 1 || imported[0].Postings[1].Account != "expenses:coffee" {
+		t.Errorf("expected the first transaction to be categorized, got %v", applied)
+	}
+	if original[1].Account != "expenses:unknown" {
+		t.Errorf("the postings of the caller were modified")
+	}
+	if imported[1].Postings[1].Account != "expenses:unknown" || imported[2].Postings[1].Account != "expenses:misc" {
+		t.Errorf("unexpected categorization %+v", imported)
+	}
+}
+
+func TestCategorizerSingleObservation(t *testing.T) {
+	c := NewCategorizer([]pta.Transaction{
+		categorizedTx("Corner bakery", "expenses:food"),
+	}, Rules{})
+	s, found := c.Suggest("Corner bakery")
+	if !found || s.Source != "payee" || s.Confidence != 0.5 {
+		t.Fatalf("expected the payee seen once at 0.5, got %v", s)
+	}
+	if s.Confident() {
+		t.Errorf("expected a single observation not to be enough")
+	}
+	imported := []pta.Transaction{categorizedTx("Corner bakery", "expenses:unknown")}
+	if applied := c.Categorize(imported); len(applied) != 0 {
+		t.Errorf("expected no category applied from a single observation, got %v", applied)
+	}
+}
+
+func TestCategorizerPayeeOfDuplicates(t *testing.T) {
+	c := NewCategorizer([]pta.Transaction{
+		categorizedTx("STARBUCKS #1234 TORONTO", "expenses:coffee"),
+		categorizedTx("STARBUCKS #1234 TORONTO", "expenses:coffee"),
+	}, Rules{})
+	// the payee of the duplicates detection, 'starbucks'
+	if normalizePayee("Starbucks") != normalizePayee("STARBUCKS #1234 TORONTO") {
+		t.Fatalf("expected the same payee")
+	}
+	if s, found := c.Suggest("Starbucks"); !found || s.Source != "payee" || s.Account != "expenses:coffee" {
+		t.Errorf("expected the payee learned from the longer description, got %v", s)
+	}
+}
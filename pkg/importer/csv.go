@@ -0,0 +1,238 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the rules didn't assign the account of the statement
+var ErrNoAccount = fmt.Errorf("missing account1")
+
+// date formats tried when the rules don't have a date-format
+var defaultDateLayouts = []string{"2006-01-02", "2006/01/02", "2006.01.02"}
+
+// ImportCSV converts the rows of a CSV bank statement into
+// balanced transactions, as described by the rules. Rows that
+// can't be converted are reported together in the error, the
+// other transactions are still returned. The rows without an
+// amount are skipped
+func ImportCSV(r io.Reader, rules Rules) ([]pta.Transaction, error) {
+	if len(rules.Fields) == 0 {
+		return nil, fmt.Errorf("rules: missing fields list")
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = rules.Separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var txs []pta.Transaction
+	var errs []error
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return txs, err
+		}
+		if row <= rules.Skip || isEmptyRecord(record) {
+			continue
+		}
+		tx, err := rules.transaction(rules.csvFields(record), record)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("csv:%d: %s", row, err))
+			continue
+		}
+		if noAmount(tx) {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+
+	if rules.NewestFirst {
+		slices.Reverse(txs)
+	}
+	return txs, errors.Join(errs...)
+}
+
+// a zero amount is written like an inferred one, the journal
+// could not be read back with all the postings empty
+func noAmount(tx pta.Transaction) bool {
+	for _, post := range tx.Postings {
+		if !post.Amount.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var interpolation = regexp.MustCompile(`%([a-zA-Z0-9_-]+)`)
+
+func (rules Rules) csvFields(record []string) map[string]string {
+	fields := make(map[string]string, len(rules.Fields))
+	for i, name := range rules.Fields {
+		if i < len(record) && name != "" && name != "_" {
+			fields[name] = strings.TrimSpace(record[i])
+		}
+	}
+	return fields
+}
+
+// builds the transaction from the named fields of a statement
+// entry, conditions match against the whole record
+func (rules Rules) transaction(fields map[string]string, record []string) (tx pta.Transaction, err error) {
+	// field values, overridden by the assignments
+	values := make(map[string]string)
+	for name, val := range fields {
+		values[name] = val
+	}
+	interpolate := func(val string) string {
+		return interpolation.ReplaceAllStringFunc(val, func(ref string) string {
+			if n, err := strconv.Atoi(ref[1:]); err == nil {
+				if n > 0 && n <= len(record) {
+					return strings.TrimSpace(record[n-1])
+				}
+				return ""
+			}
+			return fields[strings.ToLower(ref[1:])]
+		})
+	}
+	for name, val := range rules.Assign {
+		values[name] = interpolate(val)
+	}
+	joined := strings.Join(record, ",")
+	for _, cond := range rules.Conditions {
+		if cond.matches(joined, fields) {
+			for name, val := range cond.Assign {
+				values[name] = interpolate(val)
+			}
+		}
+	}
+
+	tx.Date, err = parseDate(values["date"], rules.DateFormat)
+	if err != nil {
+		return
+	}
+	tx.Description = values["description"]
+	tx.Code = values["code"]
+	tx.Pending = values["status"] == "!"
+	tx.Cleared = values["status"] == "*"
+	tx.Tags = tagsFrom(tx.Description)
+	if fitid := values["fitid"]; fitid != "" {
+		// identifies the entry, to find duplicates
+		tx.Meta = map[string]string{"fitid": fitid}
+	}
+
+	var amount decimal.Decimal
+	switch {
+	case values["amount"] != "":
+		amount, err = parseAmount(values["amount"], rules.DecimalMark)
+	case values["amount-in"] != "":
+		amount, err = parseAmount(values["amount-in"], rules.DecimalMark)
+	case values["amount-out"] != "":
+		amount, err = parseAmount(values["amount-out"], rules.DecimalMark)
+		amount = amount.Abs().Neg()
+	default:
+		err = fmt.Errorf("missing amount")
+	}
+	if err != nil {
+		return
+	}
+
+	commodity := pta.DefaultCurrency
+	if code := values["currency"]; code != "" {
+		commodity = pta.CommodityFromCode(strings.ToUpper(code))
+	}
+
+	account1 := values["account1"]
+	if account1 == "" {
+		return tx, ErrNoAccount
+	}
+	account2 := values["account2"]
+	if account2 == "" {
+		// same defaults as hledger
+		if amount.IsNegative() {
+			account2 = "expenses:unknown"
+		} else {
+			account2 = "income:unknown"
+		}
+	}
+
+	tx.Postings = []pta.Posting{{
+		Account: account1,
+		Lot:     pta.Lot{Amount: amount, Commodity: commodity},
+	}, {
+		Account: account2,
+		Lot:     pta.Lot{Amount: amount.Neg(), Commodity: commodity},
+	}}
+	return tx, nil
+}
+
+func parseDate(val, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, val)
+	}
+	for _, layout := range defaultDateLayouts {
+		if date, err := time.Parse(layout, val); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format '%s', set date-format in the rules", val)
+}
+
+// accepts amounts such as: -1,234.56  $12.00  (12.00)  12,34 €
+func parseAmount(val string, decimalMark byte) (decimal.Decimal, error) {
+	neg := strings.Contains(val, "-") ||
+		(strings.HasPrefix(val, "(") && strings.HasSuffix(val, ")"))
+
+	var sb strings.Builder
+	for i := 0; i < len(val); i++ {
+		switch {
+		case val[i] >= '0' && val[i] <= '9':
+			sb.WriteByte(val[i])
+		case val[i] == decimalMark:
+			sb.WriteByte('.')
+		}
+	}
+	amount, err := decimal.NewFromString(sb.String())
+	if err != nil {
+		return amount, fmt.Errorf("bad amount '%s'", val)
+	}
+	if neg {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// same tag syntax as transaction descriptions in the journal
+func tagsFrom(desc string) (tags []string) {
+	for _, word := range strings.Fields(desc) {
+		if len(word) > 1 && word[0] == '#' {
+			tags = append(tags, strings.TrimFunc(word[1:], func(r rune) bool {
+				return strings.ContainsRune(".,;:!?", r)
+			}))
+		}
+	}
+	return tags
+}
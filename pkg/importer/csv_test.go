@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := LoadRules("./test/bank.rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Skip != 1 {
+		t.Errorf("expected skip 1, got %d", rules.Skip)
+	}
+	if strings.Join(rules.Fields, ",") != "date,description,amount,_" {
+		t.Errorf("unexpected fields: %v", rules.Fields)
+	}
+	if rules.DateFormat != "02/01/2006" {
+		t.Errorf("unexpected date layout: %s", rules.DateFormat)
+	}
+	if rules.Assign["account1"] != "assets:tangerine:checking" {
+		t.Errorf("unexpected account1: %s", rules.Assign["account1"])
+	}
+	if len(rules.Conditions) != 3 {
+		t.Fatalf("expected 3 conditions, got %d", len(rules.Conditions))
+	}
+	if rules.Conditions[0].Assign["account2"] != "expenses:coffee" {
+		t.Errorf("single line condition not parsed: %+v", rules.Conditions[0])
+	}
+	if rules.Conditions[1].Field != "description" || len(rules.Conditions[1].Assign) != 2 {
+		t.Errorf("field condition not parsed: %+v", rules.Conditions[1])
+	}
+	if len(rules.Conditions[2].Patterns) != 2 {
+		t.Errorf("expected 2 patterns, got %d", len(rules.Conditions[2].Patterns))
+	}
+
+	_, err = ParseRules(strings.NewReader("fields date, amount\nbogus rule\n"))
+	if err == nil {
+		t.Errorf("expected error for unknown rule")
+	}
+	rules, err = ParseRules(strings.NewReader("skip 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ImportCSV(strings.NewReader("2024-01-01,1\n"), rules)
+	if err == nil {
+		t.Errorf("expected error for missing fields")
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	rules, err := LoadRules("./test/bank.rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open("./test/bank.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	txs, err := ImportCSV(f, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cad := pta.CommodityFromCode("CAD")
+	expected := []struct {
+		date    string
+		desc    string
+		account string
+		amount  string
+	}{
+		{"2024/01/15", "STARBUCKS #1234", "expenses:coffee", "-5.75"},
+		{"2024/01/16", "ACME salary", "income:acme", "1250"},
+		{"2024/01/17", "METRO GROCER", "expenses:food:groceries", "-82.10"},
+		{"2024/01/18", "Unknown shop", "expenses:unknown", "-10"},
+	}
+	if len(txs) != len(expected) {
+		t.Fatalf("expected %d transactions, got %d", len(expected), len(txs))
+	}
+	for i, exp := range expected {
+		tx := txs[i]
+		if tx.Date.Format("2006/01/02") != exp.date {
+			t.Errorf("%d: expected date %s, got %s", i, exp.date, tx.Date.Format("2006/01/02"))
+		}
+		if tx.Description != exp.desc {
+			t.Errorf("%d: expected description '%s', got '%s'", i, exp.desc, tx.Description)
+		}
+		if tx.Postings[0].Account != "assets:tangerine:checking" || tx.Postings[1].Account != exp.account {
+			t.Errorf("%d: unexpected accounts %s, %s", i, tx.Postings[0].Account, tx.Postings[1].Account)
+		}
+		amount, _ := decimal.NewFromString(exp.amount)
+		if !tx.Postings[0].Amount.Equal(amount) || !tx.Postings[1].Amount.Equal(amount.Neg()) {
+			t.Errorf("%d: expected amount %s, got %s", i, amount, tx.Postings[0].Amount)
+		}
+		if tx.Postings[0].Commodity != cad {
+			t.Errorf("%d: expected CAD, got %+v", i, tx.Postings[0].Commodity)
+		}
+	}
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(
+		"fields date, description, amount-in, amount-out\naccount1 assets:bank\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "2024-01-01,deposit,100,\n" +
+		"not a date,oops,1,\n" +
+		"2024-01-02,withdrawal,,40\n"
+
+	txs, err := ImportCSV(strings.NewReader(in), rules)
+	if err == nil || !strings.Contains(err.Error(), "csv:2:") {
+		t.Errorf("expected an error on row 2, got: %v", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+	if !txs[1].Postings[0].Amount.Equal(decimal.New(-40, 0)) {
+		t.Errorf("expected amount-out to be negative, got %s", txs[1].Postings[0].Amount)
+	}
+	if txs[0].Postings[1].Account != "income:unknown" {
+		t.Errorf("expected default income account, got %s", txs[0].Postings[1].Account)
+	}
+	if !txs[0].Date.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %s", txs[0].Date)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		in       string
+		mark     byte
+		expected string
+	}{
+		{"12", '.', "12"},
+		{"-1,234.56", '.', "-1234.56"},
+		{"$12.00", '.', "12"},
+		{"(12.50)", '.', "-12.5"},
+		{"1.234,56 €", ',', "1234.56"},
+		{"$-3", '.', "-3"},
+	}
+	for _, c := range cases {
+		got, err := parseAmount(c.in, c.mark)
+		if err != nil {
+			t.Errorf("parseAmount(%s): %s", c.in, err)
+			continue
+		}
+		expected, _ := decimal.NewFromString(c.expected)
+		if !got.Equal(expected) {
+			t.Errorf("parseAmount(%s) = %s, want %s", c.in, got, expected)
+		}
+	}
+}
+
+func TestImportCSVZeroAmount(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader("fields date, description, amount\naccount1 assets:bank\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "2024-01-02,fee waived,0.00\n" +
+		"2024-01-03,coffee,-3.00\n"
+
+	txs, err := ImportCSV(strings.NewReader(in), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].Description != "coffee" {
+		t.Fatalf("expected the zero amount row skipped, got %+v", txs)
+	}
+
+	// appended to a journal, it is read back
+	var sb strings.Builder
+	for _, tx := range txs {
+		sb.WriteString(pta.WriteTransaction(tx))
+	}
+	for _, err := range pta.NewDecoder(strings.NewReader(sb.String())).All() {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
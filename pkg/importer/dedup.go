@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// banks don't always agree on the posting date of a transaction
+// (pending vs posted), so overlapping statements may shift the
+// same entry by a few days
+var DuplicateWindow = 4 * 24 * time.Hour
+
+// Duplicate is an imported transaction that is likely
+// already in the journal
+type Duplicate struct {
+	Index    int // of the imported transaction
+	Existing pta.Transaction
+	Reason   string
+}
+
+// the statement account is the first posting of the imported
+// transactions, it may be any posting of the journal transactions
+type fingerprint struct {
+	account string
+	amount  string
+}
+
+func fingerprintOf(post pta.Posting) fingerprint {
+	return fingerprint{
+		account: strings.ToLower(post.Account),
+		amount:  post.Amount.String() + " " + post.Commodity.Code,
+	}
+}
+
+// FindDuplicates compares imported transactions with the journal's.
+// Transactions with the same fitid (or other id metadata) are
+// duplicates, otherwise the same amount on the same account within
+// the DuplicateWindow, with the same payee or on the same day. Each
+// journal transaction is the duplicate of one imported transaction
+// at most, so repeated purchases are kept
+func FindDuplicates(existing, imported []pta.Transaction) []Duplicate {
+	byID := make(map[string]int)
+	byFingerprint := make(map[fingerprint][]int)
+	for i, tx := range existing {
+		for _, id := range txIDs(tx) {
+			byID[id] = i
+		}
+		for _, post := range tx.Postings {
+			fp := fingerprintOf(post)
+			byFingerprint[fp] = append(byFingerprint[fp], i)
+		}
+	}
+
+	used := make(map[int]bool)
+	var duplicates []Duplicate
+	for index, tx := range imported {
+		match, reason := -1, ""
+		for _, id := range txIDs(tx) {
+			if i, found := byID[id]; found && !used[i] {
+				match, reason = i, fmt.Sprintf("same %s", strings.SplitN(id, ":", 2)[0])
+				break
+			}
+		}
+
+		if match == -1 && len(tx.Postings) > 0 {
+			payee := normalizePayee(tx.Description)
+			best, samePayee := DuplicateWindow+1, false
+			for _, i := range byFingerprint[fingerprintOf(tx.Postings[0])] {
+				candidate := existing[i]
+				if used[i] || hasOtherID(tx, candidate) {
+					continue
+				}
+				delta := tx.Date.Sub(candidate.Date).Abs()
+				if delta > DuplicateWindow || delta >= best {
+					continue
+				}
+				// the closest one with the same payee, or any on the same day
+				payeeMatch := payee != "" && payee == normalizePayee(candidate.Description)
+				if payeeMatch || delta == 0 {
+					match, best, samePayee = i, delta, payeeMatch
+				}
+			}
+			switch {
+			case match == -1:
+			case samePayee && best == 0:
+				reason = "same amount and payee on the same day"
+			case samePayee:
+				reason = fmt.Sprintf("same amount and payee, %d days apart", int(best.Hours()/24))
+			default:
+				reason = "same amount on the same day"
+			}
+		}
+
+		if match != -1 {
+			used[match] = true
+			duplicates = append(duplicates, Duplicate{
+				Index:    index,
+				Existing: existing[match],
+				Reason:   reason,
+			})
+		}
+	}
+	return duplicates
+}
+
+// the metadata identifying a statement entry
+var idMetaKeys = []string{"fitid", "id", "uuid"}
+
+func txIDs(tx pta.Transaction) (ids []string) {
+	for _, key := range idMetaKeys {
+		if val := tx.Meta[key]; val != "" {
+			ids = append(ids, key+":"+val)
+		}
+	}
+	return ids
+}
+
+// two entries with different ids are different, even with
+// the same amount and payee
+func hasOtherID(a, b pta.Transaction) bool {
+	for _, key := range idMetaKeys {
+		if a.Meta[key] != "" && b.Meta[key] != "" && a.Meta[key] != b.Meta[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// payees differ in case, store numbers, and extra
+// words between statements: 'STARBUCKS #1234 TORONTO'
+// and 'Starbucks' are both 'starbucks'
+func normalizePayee(desc string) string {
+	words := descriptionWords(desc)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+// SplitDuplicates separates the transactions likely in the journal
+// from the new ones
+func SplitDuplicates(existing, imported []pta.Transaction) (fresh []pta.Transaction, duplicates []Duplicate) {
+	duplicates = FindDuplicates(existing, imported)
+	isDuplicate := make(map[int]bool, len(duplicates))
+	for _, dup := range duplicates {
+		isDuplicate[dup.Index] = true
+	}
+	for i, tx := range imported {
+		if !isDuplicate[i] {
+			fresh = append(fresh, tx)
+		}
+	}
+	return fresh, duplicates
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	cad := pta.CommodityFromCode("CAD")
+	tx := func(date, desc, account string, amount int64, fitid string) pta.Transaction {
+		d, _ := time.Parse("2006-01-02", date)
+		tx := pta.Transaction{
+			Date:        d,
+			Description: desc,
+			Postings: []pta.Posting{
+				{Account: account, Lot: pta.Lot{Amount: decimal.New(amount, 0), Commodity: cad}},
+				{Account: "expenses:unknown", Lot: pta.Lot{Amount: decimal.New(-amount, 0), Commodity: cad}},
+			},
+		}
+		if fitid != "" {
+			tx.Meta = map[string]string{"fitid": fitid}
+		}
+		return tx
+	}
+
+	existing := []pta.Transaction{
+		tx("2024-01-05", "Starbucks", "assets:bank", -5, ""),
+		tx("2024-01-10", "PAYROLL", "assets:bank", 2500, "A1"),
+		tx("2024-01-12", "Metro", "assets:bank", -80, ""),
+		tx("2024-01-20", "Rent", "assets:bank", -1000, ""),
+		// manually entered, the bank account is the second posting
+		{
+			Date:        time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+			Description: "hardware store",
+			Postings: []pta.Posting{
+				{Account: "expenses:home", Lot: pta.Lot{Amount: decimal.New(30, 0), Commodity: cad}},
+				{Account: "assets:bank", Lot: pta.Lot{Amount: decimal.New(-30, 0), Commodity: cad}},
+			},
+		},
+	}
+	imported := []pta.Transaction{
+		tx("2024-01-07", "POS STARBUCKS #1234", "assets:bank", -5, ""), // payee within window
+		tx("2024-01-07", "STARBUCKS #1234", "assets:bank", -5, ""),     // second coffee, kept
+		tx("2024-01-11", "Payroll deposit", "assets:bank", 2500, "A1"), // same fitid
+		tx("2024-01-12", "MTR 042", "assets:bank", -80, ""),            // same day
+		tx("2024-01-22", "Rent", "assets:bank", -1000, "B7"),           // no id in the journal
+		tx("2024-01-15", "Metro", "assets:bank", -80, ""),              // other day, already used
+		tx("2024-01-25", "HOME HARDWARE", "assets:bank", -30, ""),      // any posting of the journal
+		tx("2024-01-26", "Rent", "assets:bank", -1000, ""),             // already used
+		tx("2024-02-05", "Starbucks", "assets:bank", -5, ""),           // out of window
+		tx("2024-01-10", "PAYROLL", "assets:bank", 2500, "A2"),         // different fitid
+	}
+
+	duplicates := FindDuplicates(existing, imported)
+	expected := map[int]string{
+		0: "same amount and payee, 2 days apart",
+		2: "same fitid",
+		3: "same amount on the same day",
+		4: "same amount and payee, 2 days apart",
+		6: "same amount on the same day",
+	}
+	if len(duplicates) != len(expected) {
+		t.Errorf("expected %d duplicates, got %d: %+v", len(expected), len(duplicates), duplicates)
+	}
+	for _, dup := range duplicates {
+		if reason, found := expected[dup.Index]; !found || reason != dup.Reason {
+			t.Errorf("unexpected duplicate %d (%s), expected '%s'", dup.Index, dup.Reason, reason)
+		}
+	}
+
+	fresh, _ := SplitDuplicates(existing, imported)
+	if len(fresh) != len(imported)-len(expected) {
+		t.Errorf("expected %d new transactions, got %d", len(imported)-len(expected), len(fresh))
+	}
+}
+
+func TestNormalizePayee(t *testing.T) {
+	cases := map[string]string{
+		"STARBUCKS #1234 TORONTO": "starbucks",
+		"POS Purchase - Metro 42": "metro",
+		"Starbucks":               "starbucks",
+		"#123":                    "",
+	}
+	for in, expected := range cases {
+		if got := normalizePayee(in); got != expected {
+			t.Errorf("normalizePayee(%s) = %s, want %s", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fireside/pkg/pta"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// OFX statements come in two flavours: 1.x is SGML, where the
+// leaf elements have no closing tags, and 2.x is XML. QFX is
+// OFX with a few extra Intuit elements. The tokenizer below
+// accepts both: an element followed by text is a leaf (with an
+// optional closing tag), anything else is an aggregate.
+//
+// The OFX elements become fields for the rules (see Rules):
+//
+//	date, amount, description, memo, code, fitid, trntype,
+//	currency, acctid, bankid, accttype
+//
+// so the rules map the OFX account to a journal account with
+// 'if %acctid 12345' and 'account1 assets:bank:checking'. The
+// FITID is kept as transaction metadata, and the statement's
+// LEDGERBAL becomes a balance assertion
+
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+func parseOFX(data []byte) (*ofxNode, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start == -1 {
+		return nil, fmt.Errorf("ofx: missing <OFX> element")
+	}
+	data = data[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+
+	for i := 0; i < len(data); {
+		lt := bytes.IndexByte(data[i:], '<')
+		if lt == -1 {
+			break
+		}
+		i += lt
+		gt := bytes.IndexByte(data[i:], '>')
+		if gt == -1 {
+			return nil, fmt.Errorf("ofx: unterminated tag")
+		}
+		tag := string(data[i+1 : i+gt])
+		i += gt + 1
+
+		// skip xml declarations and comments
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			// pop up to the matching aggregate, unclosed
+			// elements are closed along the way
+			for j := len(stack) - 1; j > 0; j-- {
+				if stack[j].name == name {
+					stack = stack[:j]
+					break
+				}
+			}
+			continue
+		}
+
+		node := &ofxNode{name: strings.ToUpper(strings.TrimSpace(tag))}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+
+		end := bytes.IndexByte(data[i:], '<')
+		if end == -1 {
+			end = len(data) - i
+		}
+		text := strings.TrimSpace(string(data[i : i+end]))
+		if text == "" {
+			stack = append(stack, node)
+			continue
+		}
+		node.value = html.UnescapeString(text)
+		i += end
+
+		closing := "</" + node.name + ">"
+		if len(data)-i >= len(closing) && strings.EqualFold(string(data[i:i+len(closing)]), closing) {
+			i += len(closing)
+		}
+	}
+	return root, nil
+}
+
+// finds all the elements with the given name, depth first
+func (n *ofxNode) findAll(name string) (found []*ofxNode) {
+	for _, child := range n.children {
+		if child.name == name {
+			found = append(found, child)
+		}
+		found = append(found, child.findAll(name)...)
+	}
+	return found
+}
+
+func (n *ofxNode) find(name string) *ofxNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// value of the first descendant with the given name
+func (n *ofxNode) get(name string) string {
+	if found := n.find(name); found != nil {
+		return found.value
+	}
+	return ""
+}
+
+// ImportOFX converts the bank and credit card statements of an
+// OFX/QFX file into transactions. Entries that can't be converted
+// are reported together in the error, the other transactions
+// are still returned
+func ImportOFX(r io.Reader, rules Rules) ([]pta.Transaction, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseOFX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the OFX fields have a known format
+	rules.DateFormat = "20060102"
+	rules.DecimalMark = '.'
+
+	var txs []pta.Transaction
+	var errs []error
+
+	statements := append(root.findAll("STMTRS"), root.findAll("CCSTMTRS")...)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("ofx: no bank or credit card statement found")
+	}
+
+	for _, stmt := range statements {
+		account := stmt.find("BANKACCTFROM")
+		if account == nil {
+			account = stmt.find("CCACCTFROM")
+		}
+		if account == nil {
+			account = &ofxNode{}
+		}
+		common := map[string]string{
+			"currency": stmt.get("CURDEF"),
+			"acctid":   account.get("ACCTID"),
+			"bankid":   account.get("BANKID"),
+			"accttype": account.get("ACCTTYPE"),
+		}
+
+		for _, entry := range stmt.findAll("STMTTRN") {
+			fields := ofxFields(common, map[string]string{
+				"date":        ofxDate(entry.get("DTPOSTED")),
+				"amount":      ofxAmount(entry.get("TRNAMT")),
+				"fitid":       entry.get("FITID"),
+				"description": firstOf(entry.get("NAME"), entry.get("PAYEE"), entry.get("MEMO")),
+				"memo":        entry.get("MEMO"),
+				"code":        entry.get("CHECKNUM"),
+				"trntype":     entry.get("TRNTYPE"),
+			})
+			if cur := entry.get("CURRENCY"); cur != "" {
+				fields["currency"] = cur
+			}
+			tx, err := rules.ofxTransaction(fields)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("ofx: %s: %s", fields["fitid"], err))
+				continue
+			}
+			if noAmount(tx) {
+				continue
+			}
+			txs = append(txs, tx)
+		}
+
+		balance := stmt.find("LEDGERBAL")
+		if balance == nil || balance.get("BALAMT") == "" {
+			continue
+		}
+		fields := ofxFields(common, map[string]string{
+			"date":        ofxDate(balance.get("DTASOF")),
+			"amount":      "0",
+			"description": "statement balance",
+		})
+		tx, err := rules.ofxTransaction(fields)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ofx: balance: %s", err))
+			continue
+		}
+		amount, err := parseAmount(ofxAmount(balance.get("BALAMT")), '.')
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ofx: balance: %s", err))
+			continue
+		}
+		// only the statement account, it asserts the
+		// balance and does not move any money
+		tx.Postings = tx.Postings[:1]
+		tx.Postings[0].Assertion = &pta.Value{
+			Decimal:   amount,
+			Commodity: tx.Postings[0].Commodity,
+		}
+		txs = append(txs, tx)
+	}
+	return txs, errors.Join(errs...)
+}
+
+func (rules Rules) ofxTransaction(fields map[string]string) (pta.Transaction, error) {
+	// conditions without a %field match against these
+	record := []string{fields["date"], fields["description"], fields["memo"], fields["amount"], fields["acctid"]}
+	tx, err := rules.transaction(fields, record)
+	if err == nil || tx.Postings != nil {
+		return tx, err
+	}
+	if errors.Is(err, ErrNoAccount) {
+		return tx, fmt.Errorf("no journal account for OFX account '%s', map it in the rules with "+
+			"'if %%acctid %s' followed by '  account1 ...'", fields["acctid"], fields["acctid"])
+	}
+	return tx, err
+}
+
+func ofxFields(common, fields map[string]string) map[string]string {
+	for key, val := range common {
+		fields[key] = val
+	}
+	return fields
+}
+
+// dates are YYYYMMDD, optionally followed by the time and timezone
+func ofxDate(val string) string {
+	if len(val) > 8 {
+		return val[:8]
+	}
+	return val
+}
+
+// some banks use a comma as the decimal mark
+func ofxAmount(val string) string {
+	if !strings.Contains(val, ".") {
+		return strings.Replace(val, ",", ".", 1)
+	}
+	return val
+}
+
+func firstOf(vals ...string) string {
+	for _, val := range vals {
+		if val != "" {
+			return val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func importOFXFile(t *testing.T, path string) []pta.Transaction {
+	rules, err := LoadRules("./test/accounts.rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	txs, err := ImportOFX(f, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txs
+}
+
+func TestImportOFX(t *testing.T) {
+	txs := importOFXFile(t, "./test/bank.ofx")
+
+	expected := []struct {
+		date    string
+		desc    string
+		account string
+		amount  string
+		fitid   string
+	}{
+		{"2024/01/05", "STARBUCKS #1234", "expenses:coffee", "-5.75", "90000001"},
+		{"2024/01/15", "PAYROLL ACME & CO", "income:salary", "2500", "90000002"},
+		{"2024/01/20", "CHEQUE", "expenses:unknown", "-100", "90000003"},
+	}
+	if len(txs) != len(expected)+1 {
+		t.Fatalf("expected %d transactions, got %d", len(expected)+1, len(txs))
+	}
+	cad := pta.CommodityFromCode("CAD")
+	for i, exp := range expected {
+		tx := txs[i]
+		if tx.Date.Format("2006/01/02") != exp.date {
+			t.Errorf("%d: expected date %s, got %s", i, exp.date, tx.Date.Format("2006/01/02"))
+		}
+		if tx.Description != exp.desc {
+			t.Errorf("%d: expected description '%s', got '%s'", i, exp.desc, tx.Description)
+		}
+		if tx.Postings[0].Account != "assets:bank:checking" || tx.Postings[1].Account != exp.account {
+			t.Errorf("%d: unexpected accounts %s, %s", i, tx.Postings[0].Account, tx.Postings[1].Account)
+		}
+		amount, _ := decimal.NewFromString(exp.amount)
+		if !tx.Postings[0].Amount.Equal(amount) || tx.Postings[0].Commodity != cad {
+			t.Errorf("%d: expected amount %s CAD, got %s %+v", i, amount, tx.Postings[0].Amount, tx.Postings[0].Commodity)
+		}
+		if tx.Meta["fitid"] != exp.fitid {
+			t.Errorf("%d: expected fitid %s, got %v", i, exp.fitid, tx.Meta)
+		}
+	}
+	if txs[2].Code != "101" {
+		t.Errorf("expected check number as code, got '%s'", txs[2].Code)
+	}
+
+	// the ledger balance becomes an assertion
+	balance := txs[3]
+	if len(balance.Postings) != 1 || balance.Postings[0].Assertion == nil {
+		t.Fatalf("expected a balance assertion, got %+v", balance.Postings)
+	}
+	if !balance.Postings[0].Assertion.Decimal.Equal(decimal.RequireFromString("2394.25")) {
+		t.Errorf("unexpected asserted balance %s", balance.Postings[0].Assertion.Decimal)
+	}
+	if balance.Date.Format("2006/01/02") != "2024/01/31" {
+		t.Errorf("unexpected balance date %s", balance.Date)
+	}
+
+	// the output is a valid journal, and the assertion holds
+	var sb strings.Builder
+	for _, tx := range txs {
+		sb.WriteString(pta.WriteTransaction(tx))
+	}
+	var parsed []pta.Transaction
+	for tx, err := range pta.NewDecoder(strings.NewReader(sb.String())).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, tx)
+	}
+	if err := pta.CheckBalanceAssertions(parsed); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportQFX(t *testing.T) {
+	txs := importOFXFile(t, "./test/card.qfx")
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+	tx := txs[0]
+	if tx.Postings[0].Account != "liabilities:visa" || tx.Postings[1].Account != "expenses:food:groceries" {
+		t.Errorf("unexpected accounts %s, %s", tx.Postings[0].Account, tx.Postings[1].Account)
+	}
+	if tx.Date.Format("2006/01/02") != "2024/01/10" || tx.Meta["fitid"] != "2024011001" {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+	if txs[1].Postings[0].Assertion == nil || !txs[1].Postings[0].Assertion.Decimal.Equal(decimal.RequireFromString("-42.10")) {
+		t.Errorf("unexpected balance assertion %+v", txs[1].Postings)
+	}
+}
+
+func TestImportOFXUnmappedAccount(t *testing.T) {
+	f, err := os.Open("./test/bank.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = ImportOFX(f, Rules{})
+	if err == nil || !strings.Contains(err.Error(), "if %acctid 123456789") {
+		t.Errorf("expected an account mapping error, got: %v", err)
+	}
+}
@@ -0,0 +1,545 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fireside/pkg/pta"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// QIF files are a list of sections ('!Type:Bank', '!Account', ...)
+// holding records of one-letter fields, each record ends with '^'.
+// Records of the Bank, CCard, Cash, Oth A, Oth L and Invst sections
+// become transactions, the Cat and Security lists are only used to
+// know income categories and security symbols.
+//
+// The records become fields for the rules (see Rules):
+//
+//	date, amount, description, memo, code, category, account, status
+//
+// QIF categories are mapped to expenses: accounts (income: for the
+// categories flagged as income), '[Account]' transfers and the
+// '!Account' names to assets: or liabilities: accounts. The rules
+// can rename them with 'if %account Chequing' or 'if %category ...'.
+// Note that transfers between two exported accounts are listed in
+// both accounts
+//
+// the journal only balances amounts of the same commodity, so buying
+// and selling securities goes through this account
+const tradingAccount = "equity:trading"
+
+// sections without splits
+var qifLists = map[string]bool{
+	"type:invst":     true,
+	"type:cat":       true,
+	"type:class":     true,
+	"type:security":  true,
+	"type:memorized": true,
+	"type:prices":    true,
+}
+
+type qifRecord struct {
+	row    int
+	fields map[byte]string
+	splits []qifSplit
+}
+
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+type qifImport struct {
+	rules Rules
+
+	// from the !Account section
+	account     string
+	accountType string
+
+	// from the !Type:Cat and !Type:Security lists
+	accountTypes map[string]string
+	incomeCats   map[string]bool
+	securities   map[string]string
+	dayFirst     bool
+	txs          []pta.Transaction
+	errs         []error
+}
+
+// ImportQIF converts the transactions of a QIF export. Records that
+// can't be converted are reported together in the error, the other
+// transactions are still returned
+func ImportQIF(r io.Reader, rules Rules) ([]pta.Transaction, error) {
+	imp := qifImport{
+		rules:        rules,
+		accountTypes: make(map[string]string),
+		incomeCats:   make(map[string]bool),
+		securities:   make(map[string]string),
+		// the date-format only tells the order of day and month
+		dayFirst: strings.Index(rules.DateFormat, "02") != -1 &&
+			strings.Index(rules.DateFormat, "02") < strings.Index(rules.DateFormat, "01"),
+	}
+	imp.rules.DateFormat = "2006-01-02"
+
+	section := ""
+	record := qifRecord{fields: make(map[byte]string)}
+
+	s := bufio.NewScanner(r)
+	for row := 1; s.Scan(); row++ {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if line == "" {
+			continue
+		}
+		if line[0] == '!' {
+			header, _, _ := strings.Cut(line, " ")
+			switch strings.ToLower(header) {
+			case "!option:autoswitch", "!clear:autoswitch":
+				// the account list is handled like any other account
+			default:
+				section = strings.ToLower(strings.TrimPrefix(line, "!"))
+			}
+			continue
+		}
+		if line[0] == '^' {
+			imp.record(section, record)
+			record = qifRecord{fields: make(map[byte]string)}
+			continue
+		}
+
+		if len(record.fields) == 0 && record.splits == nil {
+			record.row = row
+		}
+		code, val := line[0], strings.TrimSpace(line[1:])
+		// S, E and $ are splits, except in the lists and investments
+		splits := strings.HasPrefix(section, "type:") && !qifLists[section]
+		switch {
+		case code == 'S' && splits:
+			record.splits = append(record.splits, qifSplit{category: val})
+		case code == 'E' && record.splits != nil:
+			record.splits[len(record.splits)-1].memo = val
+		case code == '$' && record.splits != nil:
+			record.splits[len(record.splits)-1].amount = val
+		case code == 'A':
+			// address, one line per field
+			record.fields[code] = strings.TrimSpace(record.fields[code] + " " + val)
+		default:
+			record.fields[code] = val
+		}
+	}
+	if err := s.Err(); err != nil {
+		return imp.txs, err
+	}
+	if len(record.fields) > 0 {
+		// missing the final '^'
+		imp.record(section, record)
+	}
+	return imp.txs, errors.Join(imp.errs...)
+}
+
+func (imp *qifImport) record(section string, record qifRecord) {
+	f := record.fields
+	switch section {
+	case "account":
+		imp.account = f['N']
+		imp.accountType = f['T']
+		imp.accountTypes[f['N']] = f['T']
+		return
+	case "type:cat":
+		if _, income := f['I']; income {
+			imp.incomeCats[strings.ToLower(f['N'])] = true
+		}
+		return
+	case "type:security":
+		if f['S'] != "" {
+			imp.securities[f['N']] = f['S']
+		}
+		return
+	case "type:bank", "type:ccard", "type:cash", "type:oth a", "type:oth l", "type:invst":
+	default:
+		// memorized transactions, classes, prices, ...
+		return
+	}
+	if len(f) == 0 {
+		return
+	}
+
+	var tx pta.Transaction
+	var err error
+	if section == "type:invst" {
+		tx, err = imp.investment(record)
+	} else {
+		tx, err = imp.transaction(section, record)
+	}
+	if err != nil {
+		imp.errs = append(imp.errs, fmt.Errorf("qif:%d: %s", record.row, err))
+		return
+	}
+	if noAmount(tx) {
+		// like the CSV rows, nothing to import
+		return
+	}
+	imp.txs = append(imp.txs, tx)
+}
+
+// fields shared by all the records
+func (imp *qifImport) fields(section string, record qifRecord) (map[string]string, error) {
+	f := record.fields
+	date, err := qifDate(f['D'], imp.dayFirst)
+	if err != nil {
+		return nil, err
+	}
+	accountType := imp.accountType
+	if imp.account == "" {
+		accountType = strings.TrimPrefix(section, "type:")
+	}
+	fields := map[string]string{
+		"date":        date,
+		"amount":      firstOf(f['T'], f['U']),
+		"description": f['P'],
+		"memo":        f['M'],
+		"code":        f['N'],
+		"category":    f['L'],
+		"account":     imp.account,
+		"account1":    imp.rules.qifAccount(imp.account, accountType),
+	}
+	switch strings.ToLower(f['C']) {
+	case "*", "c", "x", "r":
+		// cleared or reconciled
+		fields["status"] = "*"
+	}
+	return fields, nil
+}
+
+func (imp *qifImport) transaction(section string, record qifRecord) (tx pta.Transaction, err error) {
+	fields, err := imp.fields(section, record)
+	if err != nil {
+		return
+	}
+	if record.splits == nil {
+		fields["account2"] = imp.category(fields["category"])
+	}
+	tx, err = imp.rules.transaction(fields, qifRecordValues(fields))
+	if err != nil {
+		return
+	}
+	if fields["memo"] != "" {
+		tx.Meta = map[string]string{"memo": fields["memo"]}
+	}
+	if record.splits == nil {
+		return
+	}
+
+	// the split categories replace the second posting
+	commodity := tx.Postings[0].Commodity
+	tx.Postings = tx.Postings[:1]
+	remaining := tx.Postings[0].Amount
+	for _, split := range record.splits {
+		amount, err := parseAmount(split.amount, imp.rules.DecimalMark)
+		if err != nil {
+			return tx, err
+		}
+		remaining = remaining.Sub(amount)
+		tx.Postings = append(tx.Postings, pta.Posting{
+			Account: firstOf(imp.category(split.category), unknownAccount(amount.Neg())),
+			Lot:     pta.Lot{Amount: amount.Neg(), Commodity: commodity},
+		})
+	}
+	if !remaining.IsZero() {
+		// splits that don't add up to the total
+		tx.Postings = append(tx.Postings, pta.Posting{
+			Account: unknownAccount(remaining.Neg()),
+			Lot:     pta.Lot{Amount: remaining.Neg(), Commodity: commodity},
+		})
+	}
+	return tx, nil
+}
+
+func (imp *qifImport) investment(record qifRecord) (tx pta.Transaction, err error) {
+	f := record.fields
+	fields, err := imp.fields("type:invst", record)
+	if err != nil {
+		return
+	}
+	action := strings.ToLower(f['N'])
+	fields["code"] = ""
+	if fields["description"] == "" {
+		fields["description"] = strings.TrimSpace(f['N'] + " " + f['Y'])
+	}
+
+	amount, err := qifDecimal(fields["amount"], imp.rules.DecimalMark)
+	if err != nil {
+		return
+	}
+	quantity, err := qifDecimal(f['Q'], imp.rules.DecimalMark)
+	if err != nil {
+		return
+	}
+	price, err := qifDecimal(f['I'], imp.rules.DecimalMark)
+	if err != nil {
+		return
+	}
+	commission, err := qifDecimal(f['O'], imp.rules.DecimalMark)
+	if err != nil {
+		return
+	}
+
+	// the postings are set below
+	fields["amount"] = "0"
+	tx, err = imp.rules.transaction(fields, qifRecordValues(fields))
+	if err != nil {
+		return
+	}
+	if fields["memo"] != "" {
+		tx.Meta = map[string]string{"memo": fields["memo"]}
+	}
+	account := tx.Postings[0].Account
+	cur := tx.Postings[0].Commodity
+	security := pta.CommodityFromCode(imp.security(f['Y']))
+
+	// the X actions move the cash to or from another account
+	cashAccount := account
+	if strings.HasSuffix(action, "x") {
+		action = strings.TrimSuffix(action, "x")
+		if fields["category"] != "" {
+			cashAccount = imp.category(fields["category"])
+		}
+	}
+
+	post := func(account string, amount decimal.Decimal, com pta.Commodity) pta.Posting {
+		return pta.Posting{Account: account, Lot: pta.Lot{Amount: amount, Commodity: com}}
+	}
+	shares := func(quantity decimal.Decimal) pta.Posting {
+		p := post(account, quantity, security)
+		p.UnitValue = pta.Value{Decimal: price, Commodity: cur}
+		return p
+	}
+	incomeAccount := map[string]string{
+		"div":     "income:dividends",
+		"intinc":  "income:interest",
+		"cglong":  "income:capital-gains",
+		"cgmid":   "income:capital-gains",
+		"cgshort": "income:capital-gains",
+		"miscinc": "income:misc",
+	}
+
+	switch action {
+	case "buy":
+		if amount.IsZero() {
+			amount = quantity.Mul(price).Add(commission)
+		}
+		tx.Postings = []pta.Posting{
+			shares(quantity),
+			post(tradingAccount, quantity.Neg(), security),
+			post(tradingAccount, amount.Sub(commission), cur),
+			post(cashAccount, amount.Neg(), cur),
+		}
+	case "sell":
+		if amount.IsZero() {
+			amount = quantity.Mul(price).Sub(commission)
+		}
+		tx.Postings = []pta.Posting{
+			shares(quantity.Neg()),
+			post(tradingAccount, quantity, security),
+			post(tradingAccount, amount.Add(commission).Neg(), cur),
+			post(cashAccount, amount, cur),
+		}
+	case "reinvdiv", "reinvint", "reinvlg", "reinvmd", "reinvsh":
+		income := map[string]string{"reinvdiv": "div", "reinvint": "intinc", "reinvlg": "cglong",
+			"reinvmd": "cgmid", "reinvsh": "cgshort"}[action]
+		tx.Postings = []pta.Posting{
+			shares(quantity),
+			post(tradingAccount, quantity.Neg(), security),
+			post(tradingAccount, amount, cur),
+			post(incomeAccount[income], amount.Neg(), cur),
+		}
+		commission = decimal.Zero
+	case "div", "intinc", "cglong", "cgmid", "cgshort", "miscinc":
+		tx.Postings = []pta.Posting{
+			post(cashAccount, amount, cur),
+			post(incomeAccount[action], amount.Neg(), cur),
+		}
+		commission = decimal.Zero
+	case "shrsin", "shrsout":
+		if action == "shrsout" {
+			quantity = quantity.Neg()
+		}
+		tx.Postings = []pta.Posting{
+			shares(quantity),
+			post("equity:transfers", quantity.Neg(), security),
+		}
+		commission = decimal.Zero
+	case "xin", "xout", "cash", "contrib", "withdrw", "miscexp", "margint":
+		if action == "xout" || action == "withdrw" || action == "miscexp" || action == "margint" {
+			amount = amount.Abs().Neg()
+		}
+		other := imp.category(fields["category"])
+		switch {
+		case action == "miscexp":
+			other = "expenses:fees"
+		case action == "margint":
+			other = "expenses:interest"
+		case fields["category"] != "":
+		case action != "cash":
+			other = "equity:transfers"
+		default:
+			other = unknownAccount(amount.Neg())
+		}
+		tx.Postings = []pta.Posting{
+			post(account, amount, cur),
+			post(other, amount.Neg(), cur),
+		}
+		commission = decimal.Zero
+	default:
+		return tx, fmt.Errorf("unsupported investment action '%s'", f['N'])
+	}
+	if !commission.IsZero() {
+		tx.Postings = append(tx.Postings, post("expenses:fees", commission, cur))
+	}
+	return tx, nil
+}
+
+// maps a QIF category, or an [Account] transfer, to a journal account
+func (imp *qifImport) category(category string) string {
+	// the class follows the '/'
+	category, _, _ = strings.Cut(category, "/")
+	if category == "" {
+		// defaults to expenses:unknown or income:unknown
+		return ""
+	}
+	if strings.HasPrefix(category, "[") && strings.HasSuffix(category, "]") {
+		name := category[1 : len(category)-1]
+		return imp.rules.qifAccount(name, imp.accountTypes[name])
+	}
+	if imp.incomeCats[strings.ToLower(category)] {
+		return "income:" + qifAccountName(category)
+	}
+	return "expenses:" + qifAccountName(category)
+}
+
+// the ticker, when the security list has one
+func (imp *qifImport) security(name string) string {
+	if symbol, found := imp.securities[name]; found {
+		name = symbol
+	}
+	// commodity codes are letters only
+	code := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, name)
+	if code == "" {
+		return "UNKNOWN"
+	}
+	return code
+}
+
+// the default account for a QIF account name, unless renamed in
+// the rules with 'if %account name'
+func (rules Rules) qifAccount(name, accountType string) (account string) {
+	prefix := "assets:"
+	switch strings.ToLower(accountType) {
+	case "ccard", "oth l":
+		prefix = "liabilities:"
+	}
+	if name == "" {
+		switch strings.ToLower(accountType) {
+		case "ccard":
+			return "liabilities:credit-card"
+		case "invst":
+			return "assets:investments"
+		case "cash":
+			return "assets:cash"
+		}
+		return prefix + "bank"
+	}
+
+	account = prefix + qifAccountName(name)
+	fields := map[string]string{"account": name}
+	for _, cond := range rules.Conditions {
+		if cond.Field == "account" && cond.Assign["account1"] != "" && cond.matches("", fields) {
+			account = cond.Assign["account1"]
+		}
+	}
+	return account
+}
+
+// 'Auto:Fuel Costs' to 'auto:fuel-costs'
+func qifAccountName(name string) string {
+	parts := strings.Split(strings.ToLower(name), ":")
+	for i, part := range parts {
+		parts[i] = strings.Join(strings.Fields(part), "-")
+	}
+	return strings.Join(parts, ":")
+}
+
+// same defaults as the CSV import, for postings without a category
+func unknownAccount(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return "income:unknown"
+	}
+	return "expenses:unknown"
+}
+
+// conditions without a %field match against these
+func qifRecordValues(fields map[string]string) []string {
+	return []string{fields["date"], fields["description"], fields["memo"], fields["amount"], fields["category"]}
+}
+
+// QIF dates are month first and have many variants:
+// 1/15/2024, 01/15/24, 1/15'24, 1/ 5' 4, 2024-01-15
+func qifDate(val string, dayFirst bool) (string, error) {
+	apostrophe := strings.Contains(val, "'")
+	normalized := strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return -1
+		case r == '\'' || r == '-' || r == '.':
+			return '/'
+		}
+		return r
+	}, val)
+
+	parts := strings.Split(normalized, "/")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("bad date '%s'", val)
+	}
+	var nums [3]int
+	for i, part := range parts {
+		_, err := fmt.Sscanf(part, "%d", &nums[i])
+		if err != nil {
+			return "", fmt.Errorf("bad date '%s'", val)
+		}
+	}
+
+	var year, month, day int
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = nums[0], nums[1], nums[2]
+	case dayFirst:
+		day, month, year = nums[0], nums[1], nums[2]
+	default:
+		month, day, year = nums[0], nums[1], nums[2]
+	}
+	if year < 100 {
+		// the apostrophe is used for the years 2000 and after
+		if apostrophe || year < 50 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day), nil
+}
+
+func qifDecimal(val string, decimalMark byte) (decimal.Decimal, error) {
+	if val == "" {
+		return decimal.Zero, nil
+	}
+	return parseAmount(val, decimalMark)
+}
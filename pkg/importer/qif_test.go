@@ -0,0 +1,187 @@
+package importer
+
+import (
+	"fireside/pkg/pta"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestImportQIF(t *testing.T) {
+	rules, err := LoadRules("./test/legacy.rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open("./test/legacy.qif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	txs, err := ImportQIF(f, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 7 {
+		t.Fatalf("expected 7 transactions, got %d", len(txs))
+	}
+
+	accounts := func(tx pta.Transaction) string {
+		var names []string
+		for _, post := range tx.Postings {
+			names = append(names, post.Account)
+		}
+		return strings.Join(names, ",")
+	}
+	expected := []struct {
+		date     string
+		desc     string
+		accounts string
+		cleared  bool
+	}{
+		{"2024/01/15", "ACME Payroll", "assets:bank:chequing,income:salary", true},
+		{"2024/01/16", "Metro", "assets:bank:chequing,expenses:groceries,expenses:household:cleaning-supplies", true},
+		{"2024/01/20", "Payment", "assets:bank:chequing,liabilities:visa", false},
+		{"2024/01/18", "STARBUCKS", "liabilities:visa,expenses:coffee", false},
+		{"2024/01/22", "BuyX Vanguard Total Stock",
+			"assets:brokerage,equity:trading,equity:trading,assets:bank:chequing,expenses:fees", false},
+		{"2024/01/29", "Div Vanguard Total Stock", "assets:brokerage,income:dividends", false},
+		{"2024/01/30", "ReinvDiv Vanguard Total Stock",
+			"assets:brokerage,equity:trading,equity:trading,income:dividends", false},
+	}
+	for i, exp := range expected {
+		tx := txs[i]
+		if tx.Date.Format("2006/01/02") != exp.date || tx.Description != exp.desc {
+			t.Errorf("%d: unexpected transaction %s %s", i, tx.Date.Format("2006/01/02"), tx.Description)
+		}
+		if accounts(tx) != exp.accounts {
+			t.Errorf("%d: expected accounts %s, got %s", i, exp.accounts, accounts(tx))
+		}
+		if tx.Cleared != exp.cleared {
+			t.Errorf("%d: expected cleared %t", i, exp.cleared)
+		}
+	}
+
+	if txs[1].Code != "101" || txs[1].Meta["memo"] != "Weekly shopping" {
+		t.Errorf("unexpected check number or memo: %+v", txs[1])
+	}
+	if !txs[1].Postings[2].Amount.Equal(decimal.New(20, 0)) {
+		t.Errorf("unexpected split amount %s", txs[1].Postings[2].Amount)
+	}
+	buy := txs[4].Postings[0]
+	if buy.Commodity.Code != "VTI" || !buy.Amount.Equal(decimal.New(10, 0)) ||
+		!buy.UnitValue.Decimal.Equal(decimal.New(100, 0)) {
+		t.Errorf("unexpected shares posting %+v", buy)
+	}
+
+	// the output is a valid, balanced journal
+	var sb strings.Builder
+	for _, tx := range txs {
+		sb.WriteString(pta.WriteTransaction(tx))
+	}
+	count := 0
+	for _, err := range pta.NewDecoder(strings.NewReader(sb.String())).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != len(txs) {
+		t.Errorf("expected %d parsed transactions, got %d", len(txs), count)
+	}
+}
+
+func TestImportQIFDefaults(t *testing.T) {
+	in := "!Type:CCard\n" +
+		"D15/01/2024\n" +
+		"T-10.00\n" +
+		"PUnknown shop\n" +
+		"^\n" +
+		"D32/13/2024\n" +
+		"T-1\n" +
+		"^\n" +
+		"!Type:Bank\n" +
+		"D2024-01-16\n" +
+		"T-30.00\n" +
+		"SRent\n" +
+		"$-25.00\n" +
+		"^\n"
+	rules, err := ParseRules(strings.NewReader("date-format %d/%m/%Y\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs, err := ImportQIF(strings.NewReader(in), rules)
+	if err == nil || !strings.Contains(err.Error(), "qif:6:") {
+		t.Errorf("expected an error on line 6, got: %v", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+	if txs[0].Date.Format("2006/01/02") != "2024/01/15" {
+		t.Errorf("expected day first date, got %s", txs[0].Date)
+	}
+	if txs[0].Postings[0].Account != "liabilities:credit-card" || txs[0].Postings[1].Account != "expenses:unknown" {
+		t.Errorf("unexpected default accounts %+v", txs[0].Postings)
+	}
+	// the splits don't add up to the total
+	last := txs[1].Postings[len(txs[1].Postings)-1]
+	if len(txs[1].Postings) != 3 || last.Account != "expenses:unknown" || !last.Amount.Equal(decimal.New(5, 0)) {
+		t.Errorf("expected the remainder of the splits, got %+v", txs[1].Postings)
+	}
+}
+
+func TestImportQIFZeroAmount(t *testing.T) {
+	in := "!Type:Bank\n" +
+		"D2024-01-02\n" +
+		"T0.00\n" +
+		"PFee waived\n" +
+		"^\n" +
+		"D2024-01-03\n" +
+		"T-3.00\n" +
+		"PCoffee\n" +
+		"LDining\n" +
+		"^\n"
+
+	txs, err := ImportQIF(strings.NewReader(in), Rules{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].Description != "Coffee" {
+		t.Fatalf("expected the zero amount record skipped, got %+v", txs)
+	}
+
+	// appended to a journal, it is read back
+	var sb strings.Builder
+	for _, tx := range txs {
+		sb.WriteString(pta.WriteTransaction(tx))
+	}
+	for _, err := range pta.NewDecoder(strings.NewReader(sb.String())).All() {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestQIFDate(t *testing.T) {
+	cases := []struct {
+		in       string
+		dayFirst bool
+		expected string
+	}{
+		{"1/15/2024", false, "2024-01-15"},
+		{"01/15/98", false, "1998-01-15"},
+		{"1/15'24", false, "2024-01-15"},
+		{" 1/ 5' 4", false, "2004-01-05"},
+		{"15/01/2024", true, "2024-01-15"},
+		{"2024-01-15", true, "2024-01-15"},
+	}
+	for _, c := range cases {
+		got, err := qifDate(c.in, c.dayFirst)
+		if err != nil || got != c.expected {
+			t.Errorf("qifDate(%s) = %s, %v, want %s", c.in, got, err, c.expected)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rules describe how to turn the rows of a bank statement
+// into transactions, using a subset of hledger's .rules syntax
+// (OFX and QIF files have their fields set by the importers):
+//
+//	skip 1
+//	separator ;
+//	fields date, description, amount
+//	date-format %d/%m/%Y
+//	decimal-mark ,
+//	currency CAD
+//	account1 assets:bank:checking
+//
+//	if /STARBUCKS/ account2 expenses:coffee
+//
+//	if %description (GROCER|MARKET)
+//	  account2 expenses:food:groceries
+//	  comment groceries
+//
+// Assignments may interpolate the row's fields with %name or %N
+// (1-based column). Conditions are regular expressions matched
+// case insensitively against the whole row (or a single field
+// with %name), later matching blocks override earlier ones
+type Rules struct {
+	Skip        int
+	Separator   rune
+	Fields      []string
+	DateFormat  string // go time layout
+	DecimalMark byte
+	NewestFirst bool
+	Assign      map[string]string
+	Conditions  []Condition
+}
+
+type Condition struct {
+	Field    string // empty to match the whole row
+	Patterns []*regexp.Regexp
+	Assign   map[string]string
+}
+
+// the values that can be set by assignments
+var assignable = map[string]bool{
+	"date":        true,
+	"description": true,
+	"code":        true,
+	"fitid":       true,
+	"comment":     true,
+	"account1":    true,
+	"account2":    true,
+	"amount":      true,
+	"amount-in":   true,
+	"amount-out":  true,
+	"currency":    true,
+	"status":      true,
+}
+
+func LoadRules(path string) (Rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	defer f.Close()
+	return ParseRules(f)
+}
+
+func ParseRules(r io.Reader) (rules Rules, err error) {
+	rules = Rules{
+		Separator:   ',',
+		DecimalMark: '.',
+		Assign:      make(map[string]string),
+	}
+
+	var cond *Condition
+	row := 0
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		row++
+		line := s.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.ContainsAny(trimmed[:1], "#;*") {
+			continue
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+		if cond != nil {
+			if indented {
+				// assignments of the current if block
+				key, val := splitDirective(trimmed)
+				if !assignable[key] {
+					return rules, fmt.Errorf("rules:%d: unknown field '%s'", row, key)
+				}
+				cond.Assign[key] = val
+				continue
+			}
+			if len(cond.Assign) == 0 && !isDirective(trimmed) {
+				// additional patterns, before the assignments
+				err = cond.addPattern(trimmed)
+				if err != nil {
+					return rules, fmt.Errorf("rules:%d: %s", row, err)
+				}
+				continue
+			}
+			rules.Conditions = append(rules.Conditions, *cond)
+			cond = nil
+		}
+
+		key, val := splitDirective(trimmed)
+		switch {
+		case key == "if":
+			cond, err = parseCondition(val)
+			if err != nil {
+				return rules, fmt.Errorf("rules:%d: %s", row, err)
+			}
+
+		case key == "skip":
+			rules.Skip = 1
+			if val != "" {
+				rules.Skip, err = strconv.Atoi(val)
+				if err != nil {
+					return rules, fmt.Errorf("rules:%d: bad skip count '%s'", row, val)
+				}
+			}
+
+		case key == "separator":
+			switch val {
+			case "TAB", "\\t":
+				rules.Separator = '\t'
+			case "SPACE":
+				rules.Separator = ' '
+			default:
+				if len(val) != 1 {
+					return rules, fmt.Errorf("rules:%d: bad separator '%s'", row, val)
+				}
+				rules.Separator = rune(val[0])
+			}
+
+		case key == "fields":
+			for _, name := range strings.Split(val, ",") {
+				rules.Fields = append(rules.Fields, strings.ToLower(strings.TrimSpace(name)))
+			}
+
+		case key == "date-format":
+			rules.DateFormat = strftimeToLayout(val)
+
+		case key == "decimal-mark":
+			if len(val) != 1 || (val[0] != '.' && val[0] != ',') {
+				return rules, fmt.Errorf("rules:%d: decimal-mark must be '.' or ','", row)
+			}
+			rules.DecimalMark = val[0]
+
+		case key == "newest-first":
+			rules.NewestFirst = true
+
+		case assignable[key]:
+			rules.Assign[key] = val
+
+		default:
+			return rules, fmt.Errorf("rules:%d: unknown rule '%s'", row, key)
+		}
+	}
+	if cond != nil {
+		rules.Conditions = append(rules.Conditions, *cond)
+	}
+	if err = s.Err(); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+func splitDirective(line string) (key, val string) {
+	key, val, _ = strings.Cut(line, " ")
+	return strings.ToLower(key), strings.TrimSpace(val)
+}
+
+func isDirective(line string) bool {
+	key, _ := splitDirective(line)
+	return key == "if" || key == "skip" || key == "separator" ||
+		key == "fields" || key == "date-format" || key == "decimal-mark" ||
+		key == "newest-first" || assignable[key]
+}
+
+// the pattern follows 'if', it's either a plain regex on its own
+// (assignments on the next lines), or a /regex/ followed by a
+// single assignment on the same line
+func parseCondition(val string) (*Condition, error) {
+	cond := &Condition{Assign: make(map[string]string)}
+	if val == "" {
+		return cond, nil
+	}
+	if strings.HasPrefix(val, "%") {
+		cond.Field, val, _ = strings.Cut(val[1:], " ")
+		cond.Field = strings.ToLower(cond.Field)
+		val = strings.TrimSpace(val)
+	}
+	if strings.HasPrefix(val, "/") {
+		end := strings.LastIndexByte(val, '/')
+		if end == 0 {
+			return nil, fmt.Errorf("missing closing '/' in '%s'", val)
+		}
+		err := cond.addPattern(val[1:end])
+		if err != nil {
+			return nil, err
+		}
+		if rest := strings.TrimSpace(val[end+1:]); rest != "" {
+			key, assigned := splitDirective(rest)
+			if !assignable[key] {
+				return nil, fmt.Errorf("unknown field '%s'", key)
+			}
+			cond.Assign[key] = assigned
+		}
+		return cond, nil
+	}
+	return cond, cond.addPattern(val)
+}
+
+func (c *Condition) addPattern(pattern string) error {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return err
+	}
+	c.Patterns = append(c.Patterns, re)
+	return nil
+}
+
+func (c *Condition) matches(record string, fields map[string]string) bool {
+	target := record
+	if c.Field != "" {
+		target = fields[c.Field]
+	}
+	for _, re := range c.Patterns {
+		if re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+var strftime = strings.NewReplacer(
+	"%Y", "2006",
+	"%y", "06",
+	"%m", "01",
+	"%-m", "1",
+	"%d", "02",
+	"%-d", "2",
+	"%e", "_2",
+	"%b", "Jan",
+	"%B", "January",
+	"%H", "15",
+	"%M", "04",
+	"%S", "05",
+	"%%", "%",
+)
+
+func strftimeToLayout(format string) string {
+	return strftime.Replace(format)
+}
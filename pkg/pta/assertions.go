@@ -0,0 +1,55 @@
+package pta
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// CheckBalanceAssertions replays the transactions in date order
+// and checks the running balance of every posting that has a
+// balance assertion (per account, per commodity)
+func CheckBalanceAssertions(txs []Transaction) error {
+	errs := ParseErrors{}
+	for _, err := range checkBalanceAssertions(txs) {
+		errs.add(err)
+	}
+	return errs.get()
+}
+
+func checkBalanceAssertions(txs []Transaction) (errs []error) {
+	// same day transactions keep their file order
+	order := make([]int, len(txs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return txs[order[i]].Date.Before(txs[order[j]].Date)
+	})
+
+	balances := make(map[string]map[string]decimal.Decimal)
+	for _, i := range order {
+		tx := txs[i]
+		for _, post := range tx.Postings {
+			acct := balances[post.Account]
+			if acct == nil {
+				acct = make(map[string]decimal.Decimal)
+				balances[post.Account] = acct
+			}
+			acct[post.Commodity.Code] = acct[post.Commodity.Code].Add(post.Amount)
+
+			if post.Assertion == nil {
+				continue
+			}
+			balance := acct[post.Assertion.Code]
+			if !balance.Equal(post.Assertion.Decimal) {
+				errs = append(errs, fmt.Errorf("%s: balance assertion failed for '%s': expected %s, got %s",
+					tx.Date.Format("2006/01/02"), post.Account,
+					commodityStringPadded(0, post.Assertion.Commodity, post.Assertion.Decimal),
+					commodityStringPadded(0, post.Assertion.Commodity, balance)))
+			}
+		}
+	}
+	return errs
+}
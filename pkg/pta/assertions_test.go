@@ -0,0 +1,65 @@
+package pta
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBalanceAssertions(t *testing.T) {
+	j := Journal{DefaultCurrency: DefaultCurrency}
+	txs, err := j.ParseTransactionStrings(
+		"2024/01/02 second, but before in the file\n" +
+			"    assets:bank    $50 = $150\n" +
+			"    income\n" +
+			"\n" +
+			"2024/01/01 first\n" +
+			"    assets:bank    $100\n" +
+			"    income\n" +
+			"\n" +
+			"2024/01/03 check\n" +
+			"    assets:bank    = $150\n" +
+			"\n" +
+			"2024/01/04 wrong\n" +
+			"    assets:bank    $0 = $10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CheckBalanceAssertions(txs)
+	if err == nil {
+		t.Fatal("expected the last assertion to fail")
+	}
+	if strings.Count(err.Error(), "balance assertion failed") != 1 ||
+		!strings.Contains(err.Error(), "2024/01/04") {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := CheckBalanceAssertions(txs[:3]); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestParseJournalKeepsFailedAssertions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statement.journal")
+	err := os.WriteFile(path, []byte("2024/01/31 statement\n"+
+		"    assets:bank    $100 = $1,000\n"+
+		"    income\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read without error, the assertion is a separate check
+	_, txs, err := ParseJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(txs))
+	}
+	err = CheckBalanceAssertions(txs)
+	if errs, ok := err.(*ParseErrors); !ok || len(errs.Unwrap()) != 1 {
+		t.Errorf("expected the failed assertion, got %v", err)
+	}
+}
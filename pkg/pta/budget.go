@@ -0,0 +1,159 @@
+package pta
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BudgetLine compares the budget of an expense account to the
+// postings of the account and its sub-accounts, in the commodity
+// of the budget
+type BudgetLine struct {
+	Account   string
+	Commodity Commodity
+	Budgeted  decimal.Decimal // including the rollover
+	Rollover  decimal.Decimal // left from the previous periods
+	Actual    decimal.Decimal
+	Remaining decimal.Decimal
+	Used      float64 // share of the budget spent, zero without a budget
+}
+
+type BudgetPeriod struct {
+	Start time.Time
+	End   time.Time // included
+	Lines []BudgetLine
+}
+
+func (l BudgetLine) Overspent() bool {
+	return l.Remaining.IsNegative()
+}
+
+// AllBudgets returns the periodic transactions of the journal and
+// its includes
+func (j Journal) AllBudgets() []Budget {
+	budgets := slices.Clone(j.Budgets)
+	for _, inc := range j.Includes {
+		budgets = append(budgets, inc.AllBudgets()...)
+	}
+	return budgets
+}
+
+// BudgetReport compares the budgets of the expense accounts to the
+// transactions, for each period from the first transaction to the
+// last. A budget counts in the report periods its own periods start
+// in: a yearly budget is all in January of a monthly report. With
+// rollover, what is left (or overspent) of a budget carries to the
+// next period
+func BudgetReport(txs []Transaction, budgets []Budget, period Period, rollover bool) []BudgetPeriod {
+	var first, last time.Time
+	for _, tx := range txs {
+		if first.IsZero() || tx.Date.Before(first) {
+			first = tx.Date
+		}
+		if tx.Date.After(last) {
+			last = tx.Date
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	type key struct {
+		account string
+		code    string
+	}
+	var keys []key
+	commodities := make(map[key]Commodity)
+	for _, budget := range budgets {
+		for _, post := range budget.Postings {
+			if !strings.Contains(post.Account, "expense") {
+				continue
+			}
+			k := key{post.Account, post.Commodity.Code}
+			if _, found := commodities[k]; !found {
+				keys = append(keys, k)
+				commodities[k] = post.Commodity
+			}
+		}
+	}
+	slices.SortFunc(keys, func(a, b key) int {
+		if c := strings.Compare(a.account, b.account); c != 0 {
+			return c
+		}
+		return strings.Compare(a.code, b.code)
+	})
+
+	var report []BudgetPeriod
+	carry := make(map[key]decimal.Decimal)
+	for start := period.Start(first); !start.After(last); start = period.Next(start) {
+		end := period.End(start)
+		budgeted := make(map[key]decimal.Decimal)
+		for _, budget := range budgets {
+			n := decimal.NewFromInt(int64(budget.occurrences(start, end)))
+			for _, post := range budget.Postings {
+				k := key{post.Account, post.Commodity.Code}
+				if _, found := commodities[k]; found {
+					budgeted[k] = budgeted[k].Add(post.Amount.Mul(n))
+				}
+			}
+		}
+		actual := make(map[key]decimal.Decimal)
+		for _, tx := range txs {
+			if tx.Date.Before(start) || tx.Date.After(end) {
+				continue
+			}
+			for _, post := range tx.Postings {
+				for _, k := range keys {
+					if k.code == post.Commodity.Code &&
+						(post.Account == k.account || strings.HasPrefix(post.Account, k.account+":")) {
+						actual[k] = actual[k].Add(post.Amount)
+					}
+				}
+			}
+		}
+
+		p := BudgetPeriod{Start: start, End: end}
+		for _, k := range keys {
+			line := BudgetLine{
+				Account:   k.account,
+				Commodity: commodities[k],
+				Budgeted:  budgeted[k],
+				Actual:    actual[k],
+			}
+			if rollover {
+				line.Rollover = carry[k]
+				line.Budgeted = line.Budgeted.Add(line.Rollover)
+			}
+			line.Remaining = line.Budgeted.Sub(line.Actual)
+			if line.Budgeted.IsPositive() {
+				line.Used = line.Actual.Div(line.Budgeted).InexactFloat64()
+			}
+			carry[k] = line.Remaining
+			if line.Budgeted.IsZero() && line.Actual.IsZero() {
+				continue
+			}
+			p.Lines = append(p.Lines, line)
+		}
+		report = append(report, p)
+	}
+	return report
+}
+
+// the number of budget periods starting within the dates, from the
+// period of the 'from' date
+func (b Budget) occurrences(start, end time.Time) int {
+	var n int
+	date := b.Period.Start(start)
+	if date.Before(start) {
+		date = b.Period.Next(date)
+	}
+	for ; !date.After(end); date = b.Period.Next(date) {
+		if b.Start.IsZero() || !date.Before(b.Period.Start(b.Start)) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,105 @@
+package pta
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const budgetJournal = `
+~ monthly  living
+	expenses:food  $400.00
+	expenses:rent  $1,000.00
+	assets:bank
+
+~ yearly from 2024/01/01
+	expenses:travel  $1,200.00
+	assets:bank
+
+2024/01/05 Groceries
+	expenses:food:groceries  $350.00
+	assets:bank
+
+2024/01/10 Rent
+	expenses:rent  $1,000.00
+	assets:bank
+
+2024/02/05 Groceries
+	expenses:food:groceries  $300.00
+	assets:bank
+
+2024/02/06 Restaurant
+	expenses:food:dining  $150.00
+	assets:bank
+
+2024/02/10 Rent
+	expenses:rent  $1,000.00
+	assets:bank
+`
+
+func TestBudgetReport(t *testing.T) {
+	journal, txs := parseTestJournal(t, budgetJournal)
+	budgets := journal.AllBudgets()
+	if len(budgets) != 2 || budgets[0].Period != Monthly || len(budgets[0].Postings) != 3 ||
+		!budgets[1].Start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected budgets %+v", budgets)
+	}
+
+	type line struct {
+		account                     string
+		budgeted, actual, remaining int64
+	}
+	check := func(p BudgetPeriod, expected []line) {
+		t.Helper()
+		if len(p.Lines) != len(expected) {
+			t.Fatalf("expected %d lines in %s, got %+v", len(expected), p.Start.Format(time.DateOnly), p.Lines)
+		}
+		for i, e := range expected {
+			l := p.Lines[i]
+			if l.Account != e.account || !l.Budgeted.Equal(decimal.New(e.budgeted, 0)) ||
+				!l.Actual.Equal(decimal.New(e.actual, 0)) || !l.Remaining.Equal(decimal.New(e.remaining, 0)) {
+				t.Errorf("expected %+v, got %+v", e, l)
+			}
+		}
+	}
+
+	report := BudgetReport(txs, budgets, Monthly, false)
+	if len(report) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(report))
+	}
+	check(report[0], []line{{"expenses:food", 400, 350, 50}, {"expenses:rent", 1000, 1000, 0}, {"expenses:travel", 1200, 0, 1200}})
+	check(report[1], []line{{"expenses:food", 400, 450, -50}, {"expenses:rent", 1000, 1000, 0}})
+	if !report[1].Lines[0].Overspent() || report[1].Lines[0].Used != 1.125 {
+		t.Errorf("expected the food overspent, got %+v", report[1].Lines[0])
+	}
+
+	report = BudgetReport(txs, budgets, Monthly, true)
+	check(report[1], []line{{"expenses:food", 450, 450, 0}, {"expenses:rent", 1000, 1000, 0}, {"expenses:travel", 1200, 0, 1200}})
+	if !report[1].Lines[0].Rollover.Equal(decimal.New(50, 0)) {
+		t.Errorf("expected a rollover of 50, got %s", report[1].Lines[0].Rollover)
+	}
+
+	// the monthly budgets of the quarter
+	report = BudgetReport(txs, budgets, Quarterly, false)
+	check(report[0], []line{{"expenses:food", 1200, 800, 400}, {"expenses:rent", 3000, 2000, 1000}, {"expenses:travel", 1200, 0, 1200}})
+}
+
+func TestParsePeriodicTransaction(t *testing.T) {
+	for _, in := range []string{
+		"~ weekly\n\texpenses:food  $10\n\tassets:bank\n",
+		"~ monthly from someday\n\texpenses:food  $10\n\tassets:bank\n",
+		"~\n\texpenses:food  $10\n\tassets:bank\n",
+		"~ monthly\n\texpenses:food  $10\n\tassets:bank  $5\n",
+	} {
+		d := NewDecoder(strings.NewReader(in))
+		var failed bool
+		for _, err := range d.All() {
+			failed = failed || err != nil
+		}
+		if !failed {
+			t.Errorf("expected an error for '%s'", in)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package pta
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CashFlowStatement explains the change of the cash in a period by
+// the counterpart accounts of the cash postings. The cash accounts
+// are the asset accounts only ever holding currencies. Inflows are
+// positive, every section is by counterpart account, one lot per
+// currency:
+//
+//   - operating: income and expenses
+//   - investing: the other assets, and trading commodities
+//   - financing: liabilities
+//   - equity: opening balances, and currency exchanges
+type CashFlowStatement struct {
+	Start     time.Time
+	End       time.Time // included
+	Opening   []Lot     // cash at the start
+	Operating map[string][]Lot
+	Investing map[string][]Lot
+	Financing map[string][]Lot
+	Equity    map[string][]Lot
+	Closing   []Lot // cash at the end
+}
+
+// ComputeCashFlowStatement returns the statement of each period,
+// from the first transaction to the last
+func ComputeCashFlowStatement(txs []Transaction, period Period) []CashFlowStatement {
+	var first, last time.Time
+	for _, tx := range txs {
+		if first.IsZero() || tx.Date.Before(first) {
+			first = tx.Date
+		}
+		if tx.Date.After(last) {
+			last = tx.Date
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	cash := cashAccounts(txs)
+
+	var statements []CashFlowStatement
+	var closing []Lot
+	for start := period.Start(first); !start.After(last); start = period.Next(start) {
+		statement := CashFlowStatement{
+			Start:     start,
+			End:       period.End(start),
+			Opening:   closing,
+			Operating: map[string][]Lot{},
+			Investing: map[string][]Lot{},
+			Financing: map[string][]Lot{},
+			Equity:    map[string][]Lot{},
+		}
+		flows := slices.Clone(closing)
+		for _, tx := range txs {
+			if tx.Date.Before(statement.Start) || tx.Date.After(statement.End) {
+				continue
+			}
+			for _, post := range tx.Postings {
+				if cash[post.Account] {
+					flows = append(flows, post.Lot)
+				}
+			}
+			statement.addTransaction(tx, cash)
+		}
+		for _, section := range []map[string][]Lot{statement.Operating, statement.Investing, statement.Financing, statement.Equity} {
+			for account, lots := range section {
+				section[account] = aggregateLotsPerCode(lots)
+			}
+		}
+		closing = aggregateLotsPerCode(flows)
+		statement.Closing = closing
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// the cash of a transaction goes to the sections of its counterpart
+// postings in the currencies of the cash, then to the ones with a
+// unit value in these currencies ('10 VTI @ $100') for the cash
+// left to explain
+func (s *CashFlowStatement) addTransaction(tx Transaction, cash map[string]bool) {
+	residual := make(map[string]decimal.Decimal)
+	trade := false
+	for _, post := range tx.Postings {
+		if cash[post.Account] {
+			residual[post.Commodity.Code] = residual[post.Commodity.Code].Add(post.Amount)
+		}
+		if post.Commodity.Type != CURRENCY && !isVirtual(post.Account) {
+			trade = true
+		}
+	}
+	if len(residual) == 0 {
+		return
+	}
+	add := func(account string, amount decimal.Decimal, commodity Commodity) {
+		section := s.section(account, trade)
+		section[account] = append(section[account], Lot{Amount: amount.Neg(), Commodity: commodity})
+		residual[commodity.Code] = residual[commodity.Code].Add(amount)
+	}
+	for _, post := range tx.Postings {
+		if _, found := residual[post.Commodity.Code]; found && !cash[post.Account] && !isVirtual(post.Account) {
+			add(post.Account, post.Amount, post.Commodity)
+		}
+	}
+	for _, post := range tx.Postings {
+		if _, found := residual[post.Commodity.Code]; found || cash[post.Account] || isVirtual(post.Account) {
+			continue
+		}
+		if left, found := residual[post.UnitValue.Code]; found && !left.IsZero() && !post.UnitValue.Decimal.IsZero() {
+			add(post.Account, post.Amount.Mul(post.UnitValue.Decimal), post.UnitValue.Commodity)
+		}
+	}
+}
+
+func (s *CashFlowStatement) section(account string, trade bool) map[string][]Lot {
+	switch {
+	case account == tradingAccount && trade:
+		return s.Investing
+	case strings.Contains(account, "income") || strings.Contains(account, "revenue") ||
+		strings.Contains(account, "expense"):
+		return s.Operating
+	case strings.Contains(account, "asset"):
+		return s.Investing
+	case strings.Contains(account, "liability") || strings.Contains(account, "liabilities"):
+		return s.Financing
+	case strings.Contains(account, "equity"):
+		return s.Equity
+	}
+	return s.Operating
+}
+
+// NetCashFlow is the sum of the sections, one lot per currency
+func (s CashFlowStatement) NetCashFlow() []Lot {
+	var lots []Lot
+	for _, section := range []map[string][]Lot{s.Operating, s.Investing, s.Financing, s.Equity} {
+		for _, account := range slices.Sorted(maps.Keys(section)) {
+			lots = append(lots, section[account]...)
+		}
+	}
+	return aggregateLotsPerCode(lots)
+}
+
+// Check verifies that the opening balance and the cash flow add up
+// to the closing balance, which fails when cash moved against a
+// commodity without a unit value in its currency
+func (s CashFlowStatement) Check() error {
+	expected := make(map[string]decimal.Decimal)
+	for _, lot := range append(slices.Clone(s.Opening), s.NetCashFlow()...) {
+		expected[lot.Commodity.Code] = expected[lot.Commodity.Code].Add(lot.Amount)
+	}
+	for _, lot := range s.Closing {
+		expected[lot.Commodity.Code] = expected[lot.Commodity.Code].Sub(lot.Amount)
+	}
+	for _, code := range slices.Sorted(maps.Keys(expected)) {
+		if diff := expected[code]; !diff.IsZero() {
+			return fmt.Errorf("%s - %s: the cash flow is off by %s %s",
+				s.Start.Format("2006/01/02"), s.End.Format("2006/01/02"), diff, code)
+		}
+	}
+	return nil
+}
+
+// the asset accounts only ever holding currencies
+func cashAccounts(txs []Transaction) map[string]bool {
+	cash := make(map[string]bool)
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			if !strings.Contains(post.Account, "asset") || isVirtual(post.Account) {
+				continue
+			}
+			held, found := cash[post.Account]
+			cash[post.Account] = (held || !found) && post.Commodity.Type == CURRENCY
+		}
+	}
+	for account, held := range cash {
+		if !held {
+			delete(cash, account)
+		}
+	}
+	return cash
+}
+
+// '[account]' and '(account)' are virtual postings
+func isVirtual(account string) bool {
+	return strings.HasPrefix(account, "[") || strings.HasPrefix(account, "(")
+}
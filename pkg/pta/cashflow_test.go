@@ -0,0 +1,89 @@
+package pta
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+const cashFlowJournal = `
+2024/01/01 Opening
+	assets:bank  $1,000.00
+	equity:opening
+
+2024/01/15 Payroll
+	assets:bank  $3,000.00
+	income:salary
+
+2024/01/20 Rent
+	expenses:rent  $1,200.00
+	assets:bank
+
+2024/02/01 Loan
+	assets:bank  $5,000.00
+	liabilities:loan
+
+2024/02/02 Buy
+	assets:brokerage:vti   10 VTI @ $100.00
+	equity:trading        -10 VTI
+	equity:trading         $1,000.00
+	assets:bank           -$1,000.00
+
+2024/02/03 Transfer
+	assets:savings  $500.00
+	assets:bank
+
+2024/02/10 Repay
+	liabilities:loan  $200.00
+	assets:bank
+`
+
+func TestComputeCashFlowStatement(t *testing.T) {
+	_, txs := parseTestJournal(t, cashFlowJournal)
+	statements := ComputeCashFlowStatement(txs, Monthly)
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(statements))
+	}
+
+	amount := func(lots []Lot) decimal.Decimal {
+		if len(lots) != 1 {
+			t.Fatalf("expected a lot, got %v", lots)
+		}
+		return lots[0].Amount
+	}
+	jan, feb := statements[0], statements[1]
+	expected := map[string][2]decimal.Decimal{
+		"jan opening":     {decimal.Zero, decimal.Zero},
+		"jan salary":      {amount(jan.Operating["income:salary"]), decimal.New(3000, 0)},
+		"jan rent":        {amount(jan.Operating["expenses:rent"]), decimal.New(-1200, 0)},
+		"jan equity":      {amount(jan.Equity["equity:opening"]), decimal.New(1000, 0)},
+		"jan closing":     {amount(jan.Closing), decimal.New(2800, 0)},
+		"feb opening":     {amount(feb.Opening), decimal.New(2800, 0)},
+		"feb loan":        {amount(feb.Financing["liabilities:loan"]), decimal.New(4800, 0)},
+		"feb investments": {amount(feb.Investing["equity:trading"]), decimal.New(-1000, 0)},
+		"feb net":         {amount(feb.NetCashFlow()), decimal.New(3800, 0)},
+		"feb closing":     {amount(feb.Closing), decimal.New(6600, 0)},
+	}
+	if len(jan.Opening) != 0 {
+		t.Errorf("expected no cash before the first month, got %v", jan.Opening)
+	}
+	for name, values := range expected {
+		if !values[0].Equal(values[1]) {
+			t.Errorf("expected %s of %s, got %s", name, values[1], values[0])
+		}
+	}
+	// the transfer between cash accounts is not a flow
+	if len(feb.Investing) != 1 || len(feb.Operating) != 0 || len(feb.Equity) != 0 {
+		t.Errorf("unexpected february sections %+v", feb)
+	}
+
+	for _, statement := range statements {
+		if err := statement.Check(); err != nil {
+			t.Error(err)
+		}
+	}
+	feb.Closing = []Lot{{Amount: decimal.New(6000, 0), Commodity: feb.Closing[0].Commodity}}
+	if err := feb.Check(); err == nil {
+		t.Error("expected the check to fail")
+	}
+}
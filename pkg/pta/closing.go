@@ -0,0 +1,166 @@
+package pta
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Closing the books splits a journal per year: the closing
+// transaction zeroes the income statement accounts into the
+// retained earnings at the end of the year, and the opening
+// transaction starts the file of the next year with the balances
+// of the assets and liabilities
+const (
+	RetainedEarnings = "equity:retained-earnings"
+	OpeningBalances  = "equity:opening-balances"
+)
+
+// ClosingTransaction returns the transaction zeroing the balances
+// of the revenue and expense accounts up to the end date (included),
+// false when they are all zero
+func ClosingTransaction(txs []Transaction, end time.Time) (Transaction, bool) {
+	balances := make(map[string][]Lot)
+	for _, tx := range txs {
+		if tx.Date.After(end) {
+			continue
+		}
+		for _, post := range tx.Postings {
+			if isIncomeStatementAccount(post.Account) {
+				balances[post.Account] = append(balances[post.Account], Lot{Amount: post.Amount, Commodity: post.Commodity})
+			}
+		}
+	}
+
+	tx := Transaction{Date: end, Description: "closing the books"}
+	var retained []Lot
+	for _, account := range slices.Sorted(maps.Keys(balances)) {
+		for _, lot := range sumPerCode(balances[account]) {
+			tx.Postings = append(tx.Postings, Posting{Account: account, Lot: Lot{Amount: lot.Amount.Neg(), Commodity: lot.Commodity}})
+			retained = append(retained, lot)
+		}
+	}
+	if len(tx.Postings) == 0 {
+		return Transaction{}, false
+	}
+	for _, lot := range sumPerCode(retained) {
+		tx.Postings = append(tx.Postings, Posting{Account: RetainedEarnings, Lot: lot})
+	}
+	return tx, true
+}
+
+// OpeningTransaction returns the balances of the assets and the
+// liabilities at the end date, against the opening balances, dated
+// the next day. The commodities keep the average cost of their
+// purchases, '10 VTI @ $100.00'
+func OpeningTransaction(txs []Transaction, end time.Time) (Transaction, bool) {
+	type key struct {
+		account string
+		code    string
+	}
+	balances := make(map[key]Lot)
+	bought := make(map[key]decimal.Decimal)
+	for _, tx := range txs {
+		if tx.Date.After(end) {
+			continue
+		}
+		for _, post := range tx.Postings {
+			if !isBalanceSheetAccount(post.Account) {
+				continue
+			}
+			k := key{post.Account, post.Commodity.Code}
+			lot := balances[k]
+			lot.Commodity = post.Commodity
+			lot.Amount = lot.Amount.Add(post.Amount)
+			// the cost of the purchases, in a single commodity
+			if post.Commodity.Type != CURRENCY && post.Amount.IsPositive() && !post.UnitValue.Decimal.IsZero() &&
+				(lot.UnitValue.Code == "" || lot.UnitValue.Code == post.UnitValue.Code) {
+				cost := lot.UnitValue.Decimal.Mul(bought[k]).Add(post.UnitValue.Decimal.Mul(post.Amount))
+				bought[k] = bought[k].Add(post.Amount)
+				lot.UnitValue = Value{Decimal: cost.Div(bought[k]), Commodity: post.UnitValue.Commodity}
+			}
+			balances[k] = lot
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(balances), func(a, b key) int {
+		if c := strings.Compare(a.account, b.account); c != 0 {
+			return c
+		}
+		return strings.Compare(a.code, b.code)
+	})
+	tx := Transaction{Date: end.AddDate(0, 0, 1), Description: "opening balances"}
+	var equity []Lot
+	for _, k := range keys {
+		lot := balances[k]
+		if lot.Amount.IsZero() {
+			continue
+		}
+		tx.Postings = append(tx.Postings, Posting{Account: k.account, Lot: lot})
+		equity = append(equity, Lot{Amount: lot.Amount.Neg(), Commodity: lot.Commodity})
+	}
+	if len(tx.Postings) == 0 {
+		return Transaction{}, false
+	}
+	for _, lot := range sumPerCode(equity) {
+		tx.Postings = append(tx.Postings, Posting{Account: OpeningBalances, Lot: lot})
+	}
+	return tx, true
+}
+
+// OpeningBalance splits the opening transactions (the ones posting
+// to the opening balances) from the others, as the starting balance
+// of ComputeBalanceStatement. The lots are dated the last opening,
+// for the valuations at that date
+func OpeningBalance(txs []Transaction) (BalanceStatement, []Transaction) {
+	var opening, rest []Transaction
+	var date time.Time
+	for _, tx := range txs {
+		if slices.ContainsFunc(tx.Postings, func(p Posting) bool { return p.Account == OpeningBalances }) {
+			opening = append(opening, tx)
+			if tx.Date.After(date) {
+				date = tx.Date
+			}
+		} else {
+			rest = append(rest, tx)
+		}
+	}
+	start := ComputeBalanceStatement(BalanceStatement{}, opening)
+	for _, accounts := range []map[string][]Lot{start.assets, start.liabilities} {
+		for _, lots := range accounts {
+			for i := range lots {
+				lots[i].Date = date
+			}
+		}
+	}
+	return start, rest
+}
+
+// the accounts of ComputeBalanceStatement
+func isBalanceSheetAccount(account string) bool {
+	return !isVirtual(account) && (strings.Contains(account, "asset") ||
+		strings.Contains(account, "liability") || strings.Contains(account, "liabilities"))
+}
+
+// the accounts of ComputeIncomeStatement
+func isIncomeStatementAccount(account string) bool {
+	return !isVirtual(account) && (strings.Contains(account, "income") ||
+		strings.Contains(account, "revenue") || strings.Contains(account, "expense"))
+}
+
+// the non-zero sums of the amounts, by code in order of appearance
+func sumPerCode(lots []Lot) []Lot {
+	var sums []Lot
+	for _, lot := range lots {
+		i := slices.IndexFunc(sums, func(sum Lot) bool { return sum.Commodity.Code == lot.Commodity.Code })
+		if i == -1 {
+			sums = append(sums, Lot{Commodity: lot.Commodity})
+			i = len(sums) - 1
+		}
+		sums[i].Amount = sums[i].Amount.Add(lot.Amount)
+	}
+	return slices.DeleteFunc(sums, func(sum Lot) bool { return sum.Amount.Equal(decimal.Zero) })
+}
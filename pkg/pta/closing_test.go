@@ -0,0 +1,143 @@
+package pta
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const closingJournal = `
+2024/01/01 Opening
+	assets:bank  $1,000.00
+	equity:opening
+
+2024/01/15 Payroll
+	assets:bank  $3,000.00
+	income:salary
+
+2024/02/01 Buy
+	assets:brokerage:vti   10 VTI @ $100.00
+	equity:trading        -10 VTI
+	equity:trading         $1,000.00
+	assets:bank           -$1,000.00
+
+2024/03/01 Buy
+	assets:brokerage:vti   10 VTI @ $110.00
+	equity:trading        -10 VTI
+	equity:trading         $1,100.00
+	assets:bank           -$1,100.00
+
+2024/06/20 Rent
+	expenses:rent  $1,200.00
+	assets:bank
+
+2024/07/01 Card
+	expenses:food  $50.00
+	liabilities:card
+
+2025/01/05 Next year
+	expenses:food  $10.00
+	assets:bank
+`
+
+func TestClosingTransaction(t *testing.T) {
+	_, txs := parseTestJournal(t, closingJournal)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	closing, ok := ClosingTransaction(txs, end)
+	if !ok {
+		t.Fatal("expected a closing transaction")
+	}
+	expected := "2024/12/31  closing the books\r\n" +
+		"\texpenses:food             - $ 50.00\r\n" +
+		"\texpenses:rent             - $1,200.00\r\n" +
+		"\tincome:salary               $3,000.00\r\n" +
+		"\tequity:retained-earnings  - $1,750.00\r\n\r\n"
+	if got := WriteTransaction(closing); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if err := balanceTransaction(&closing); err != nil {
+		t.Error(err)
+	}
+
+	// nothing left to close
+	closed := append(append([]Transaction{}, txs[:6]...), closing)
+	if _, ok := ClosingTransaction(closed, end); ok {
+		t.Error("expected no closing transaction after closing")
+	}
+}
+
+func TestOpeningTransaction(t *testing.T) {
+	_, txs := parseTestJournal(t, closingJournal)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	opening, ok := OpeningTransaction(txs, end)
+	if !ok {
+		t.Fatal("expected an opening transaction")
+	}
+	if !opening.Date.Equal(end.AddDate(0, 0, 1)) || len(opening.Postings) != 5 {
+		t.Fatalf("unexpected opening transaction\n%s", WriteTransaction(opening))
+	}
+	vti := opening.Postings[1]
+	if vti.Account != "assets:brokerage:vti" || !vti.Amount.Equal(decimal.New(20, 0)) ||
+		!vti.UnitValue.Decimal.Equal(decimal.New(105, 0)) {
+		t.Errorf("expected 20 VTI at the average cost of $105, got %+v", vti)
+	}
+	if err := balanceTransaction(&opening); err != nil {
+		t.Error(err)
+	}
+
+	// the file of the next year starts from the opening balances
+	_, next := parseTestJournal(t, WriteTransaction(opening)+WriteTransaction(txs[len(txs)-1]))
+	start, rest := OpeningBalance(next)
+	if len(rest) != 1 {
+		t.Fatalf("expected the opening transaction apart, got %d transactions", len(rest))
+	}
+	continued := ComputeBalanceStatement(start, rest)
+	all := ComputeBalanceStatement(BalanceStatement{}, txs)
+	for _, account := range []string{"assets:bank", "assets:brokerage:vti"} {
+		got, want := continued.assets[account], all.assets[account]
+		if len(got) != 1 || len(want) != 1 || !got[0].Amount.Equal(want[0].Amount) {
+			t.Errorf("expected %s to continue at %v, got %v", account, want, got)
+		}
+	}
+	if got := continued.liabilities["liabilities:card"]; len(got) != 1 || !got[0].Amount.Equal(decimal.New(-50, 0)) {
+		t.Errorf("expected the card to continue, got %v", got)
+	}
+}
+
+func TestComputeBalanceStatementSoldOut(t *testing.T) {
+	_, txs := parseTestJournal(t, strings.Join([]string{
+		"2024/01/01 Buy\n\tassets:vti  10 VTI @ $100.00\n\tequity:trading\n",
+		"2024/02/01 Sell\n\tassets:vti  -10 VTI @ $120.00\n\tequity:trading\n",
+	}, "\n"))
+	statement := ComputeBalanceStatement(BalanceStatement{}, txs)
+	if lots := statement.assets["assets:vti"]; len(lots) != 1 || !lots[0].Amount.IsZero() {
+		t.Errorf("expected no VTI left, got %v", lots)
+	}
+}
+
+func TestOpeningBalanceValuation(t *testing.T) {
+	_, txs := parseTestJournal(t, closingJournal)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	opening, _ := OpeningTransaction(txs, end)
+	_, next := parseTestJournal(t, WriteTransaction(opening)+WriteTransaction(txs[len(txs)-1]))
+
+	v := Valuation{Mode: ValueAtCost, Target: DefaultCurrency, Prices: NewPriceHistory(nil)}
+	all := v.BalanceStatement(BalanceStatement{}, txs)
+	continued := v.BalanceStatement(OpeningBalance(next))
+	if !continued.Total.Equal(all.Total) || len(continued.Warnings) != 0 {
+		t.Errorf("expected the net worth to continue at %s, got %s %v", all.Total, continued.Total, continued.Warnings)
+	}
+
+	start, rest := OpeningBalance(next)
+	series := NetWorthSeries(start, rest, nil, Monthly, DefaultCurrency)
+	if len(series) != 1 || !series[0].Date.Equal(time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected a single month from the opening, got %+v", series)
+	}
+	// no price for the VTI, the cash and the card continue
+	if point := series[0]; !point.Assets.Equal(decimal.New(690, 0)) || !point.Liabilities.Equal(decimal.New(50, 0)) ||
+		len(point.Missing) != 1 || point.Missing[0] != "VTI" {
+		t.Errorf("expected $690.00 of assets and $50.00 of liabilities without VTI, got %+v", point)
+	}
+}
@@ -0,0 +1,303 @@
+package pta
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// go test ./pkg/pta -run TestCompatCorpus -record
+var recordGolden = flag.Bool("record", false, "record the balances of hledger and ledger in test/compat")
+
+// the balance reports of the reference tools, recorded next to the
+// journal as <journal>.<tool>.golden
+var compatTools = []struct {
+	name string
+	args []string
+}{
+	{"hledger", []string{"bal", "--flat", "--no-total", "-O", "csv"}},
+	{"ledger", []string{"bal", "--flat", "--no-total"}},
+}
+
+// The journals of test/compat use the ledger and hledger syntax
+// fireside accepts, the golden files are the balance reports of
+// hledger and ledger for them (whichever accepts the journal).
+// Record them with -record, on a machine with the tools installed.
+// The <journal>.expected files are the balances checked by hand,
+// every journal needs one or a recorded report
+func TestCompatCorpus(t *testing.T) {
+	paths, err := filepath.Glob("./test/compat/*.journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("missing test/compat journals")
+	}
+
+	for _, path := range paths {
+		_, txs, err := ParseJournal(path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		got := balanceTotals(txs)
+
+		// the parallel parse must agree
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, chunked, _ := parseJournalChunks(path, data, 1)
+		if !equalTotals(balanceTotals(chunked), got) {
+			t.Errorf("%s: the chunked parse differs:\n%v", path, balanceTotals(chunked))
+		}
+
+		compared := false
+		expectedPath := strings.TrimSuffix(path, ".journal") + ".expected"
+		if data, err := os.ReadFile(expectedPath); err == nil {
+			compared = true
+			expected, err := parseExpectedTotals(string(data))
+			if err != nil {
+				t.Fatalf("%s: %s", expectedPath, err)
+			}
+			if !equalTotals(got, expected) {
+				t.Errorf("%s: expected\n%s\ngot\n%s", path, formatTotals(expected), formatTotals(got))
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal(err)
+		}
+
+		for _, tool := range compatTools {
+			golden := strings.TrimSuffix(path, ".journal") + "." + tool.name + ".golden"
+			if *recordGolden {
+				recordBalance(t, tool.name, append([]string{"-f", path}, tool.args...), golden)
+			}
+			report, err := os.ReadFile(golden)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			compared = true
+
+			var expected map[string]decimal.Decimal
+			if tool.name == "hledger" {
+				expected, err = parseHledgerBalance(string(report))
+			} else {
+				expected, err = parseLedgerBalance(string(report))
+			}
+			if err != nil {
+				t.Fatalf("%s: %s", golden, err)
+			}
+			if !equalTotals(got, expected) {
+				t.Errorf("%s: %s expected\n%s\ngot\n%s", path, tool.name, formatTotals(expected), formatTotals(got))
+			}
+		}
+		if !compared {
+			t.Errorf("%s: no expected balances, add %s or record the tools' reports with -record", path, expectedPath)
+		}
+	}
+}
+
+// runs the tool, the output of a journal it rejects isn't recorded
+func recordBalance(t *testing.T, tool string, args []string, golden string) {
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Logf("%s not installed, %s not recorded", tool, golden)
+		return
+	}
+	out, err := exec.Command(tool, args...).Output()
+	if err != nil {
+		t.Logf("%s rejects the journal, %s not recorded (%s)", tool, golden, err)
+		os.Remove(golden)
+		return
+	}
+	if err := os.WriteFile(golden, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// '"account","balance"' rows, the commodities of a balance are
+// separated by commas: '"assets:broker","$2,600.00, 10 VTI"'
+func parseHledgerBalance(report string) (map[string]decimal.Decimal, error) {
+	rows, err := csv.NewReader(strings.NewReader(report)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]decimal.Decimal)
+	for _, row := range rows {
+		if len(row) != 2 || row[0] == "account" {
+			continue
+		}
+		// the thousands separators aren't followed by a space
+		for _, amount := range strings.Split(row[1], ", ") {
+			if err := addReportAmount(totals, row[0], amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return totals, nil
+}
+
+// the amounts are right aligned, one per line, the account follows
+// the last amount of its balance:
+//
+//	$2,600.00
+//	   10 VTI  assets:broker
+func parseLedgerBalance(report string) (map[string]decimal.Decimal, error) {
+	totals := make(map[string]decimal.Decimal)
+	var amounts []string
+	for _, line := range strings.Split(report, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "---") {
+			continue
+		}
+		amount, account, found := strings.Cut(line, "  ")
+		amounts = append(amounts, amount)
+		if !found {
+			continue
+		}
+		for _, amount := range amounts {
+			if err := addReportAmount(totals, strings.TrimSpace(account), amount); err != nil {
+				return nil, err
+			}
+		}
+		amounts = nil
+	}
+	return totals, nil
+}
+
+// the lines of formatTotals, 'amount  account  code', and comments
+func parseExpectedTotals(data string) (map[string]decimal.Decimal, error) {
+	totals := make(map[string]decimal.Decimal)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		amount, key, _ := strings.Cut(line, "  ")
+		value, err := decimal.NewFromString(amount)
+		if err != nil {
+			return nil, fmt.Errorf("bad amount '%s'", amount)
+		}
+		key = strings.TrimSpace(key)
+		totals[key] = totals[key].Add(value)
+	}
+	return totals, nil
+}
+
+func addReportAmount(totals map[string]decimal.Decimal, account, amount string) error {
+	amount = strings.TrimSpace(amount)
+	if amount == "" || amount == "0" {
+		return nil
+	}
+	// '$-5,100.00' and '-10 VTI'
+	neg := strings.Contains(amount, "-")
+	amount = strings.Replace(amount, "-", "", 1)
+	s := Scanner{journal: &Journal{DefaultCurrency: DefaultCurrency}}
+	lot, tail, err := s.ParseLot([]byte(amount))
+	if err != nil {
+		return fmt.Errorf("amount '%s': %w", amount, err)
+	}
+	if len(tail) > 0 {
+		return fmt.Errorf("unexpected '%s' after the amount '%s'", tail, amount)
+	}
+	if neg {
+		lot.Amount = lot.Amount.Neg()
+	}
+	key := account + "  " + lot.Commodity.Code
+	totals[key] = totals[key].Add(lot.Amount)
+	return nil
+}
+
+func TestCompatErrors(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{"year 24\n", "4 digit year"},
+		{"Y twenty\n", "4 digit year"},
+		{"end apply account\n", "without apply account"},
+		{"apply account \n", "must be followed by an account"},
+		// the block runs to the end of the file
+		{"comment\n2024/01/01 x\n  a  $1\n", ""},
+		{"payee ACME\n  alias A\ntag x\n  check y\n", ""},
+	}
+	for _, c := range cases {
+		var errs []string
+		for _, err := range NewDecoder(strings.NewReader(c.in)).All() {
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		got := strings.Join(errs, "\n")
+		if (c.expected == "" && got != "") || !strings.Contains(got, c.expected) {
+			t.Errorf("%q: expected error %q, got %q", c.in, c.expected, got)
+		}
+	}
+}
+
+// the non-zero balances, by account and commodity
+func balanceTotals(txs []Transaction) map[string]decimal.Decimal {
+	totals := make(map[string]decimal.Decimal)
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			key := post.Account + "  " + post.Commodity.Code
+			totals[key] = totals[key].Add(post.Amount)
+		}
+	}
+	maps.DeleteFunc(totals, func(_ string, amount decimal.Decimal) bool {
+		return amount.IsZero()
+	})
+	return totals
+}
+
+func equalTotals(a, b map[string]decimal.Decimal) bool {
+	return maps.EqualFunc(a, b, decimal.Decimal.Equal)
+}
+
+func formatTotals(totals map[string]decimal.Decimal) string {
+	var sb strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(totals)) {
+		fmt.Fprintf(&sb, "%12s  %s\n", totals[key].StringFixed(2), key)
+	}
+	return sb.String()
+}
+
+func TestParseToolBalances(t *testing.T) {
+	expected := map[string]decimal.Decimal{
+		"assets:broker  USD":   decimal.RequireFromString("2600"),
+		"assets:broker  VTI":   decimal.RequireFromString("10"),
+		"assets:checking  USD": decimal.RequireFromString("-5100"),
+	}
+
+	hledger, err := parseHledgerBalance(`"account","balance"` + "\n" +
+		`"assets:broker","$2,600.00, 10 VTI"` + "\n" +
+		`"assets:checking","$-5,100.00"` + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalTotals(hledger, expected) {
+		t.Errorf("hledger: expected\n%s\ngot\n%s", formatTotals(expected), formatTotals(hledger))
+	}
+
+	ledger, err := parseLedgerBalance("           $2,600.00\n" +
+		"              10 VTI  assets:broker\n" +
+		"          $-5,100.00  assets:checking\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalTotals(ledger, expected) {
+		t.Errorf("ledger: expected\n%s\ngot\n%s", formatTotals(expected), formatTotals(ledger))
+	}
+}
@@ -54,7 +54,9 @@ func isCurrencyCode(code string) bool {
 	return ok
 }
 
-func commodityFromCode(code string) (com Commodity) {
+// CommodityFromCode returns a currency for known currency
+// codes, anything else is considered a stock
+func CommodityFromCode(code string) (com Commodity) {
 	com.Code = code
 	if isCurrencyCode(code) {
 		com.Type = CURRENCY
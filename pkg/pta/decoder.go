@@ -0,0 +1,260 @@
+package pta
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Decoder reads a journal one transaction at a time
+//
+// ParseJournal needs the whole journal in memory, but
+// the decoder only holds the transaction currently being
+// parsed (plus the state of any open include), so very
+// large journals can be processed with bounded memory,
+// and journals can be read from any io.Reader (such as
+// an uploaded request body) without touching the disk
+type Decoder struct {
+	s       Scanner
+	journal *Journal
+	fsys    fs.FS
+	inc     *Decoder
+	closer  io.Closer
+	done    bool
+
+	// includes parsed ahead of time, by row (see parser_parallel.go)
+	prefetched map[int]*prefetch
+	replay     *prefetch
+}
+
+// NewDecoder returns a decoder reading from r. Include
+// directives are resolved on the local filesystem, relative
+// to the current working directory, unless WithFS is used
+func NewDecoder(r io.Reader) *Decoder {
+	return newDecoder(r, "", nil)
+}
+
+// NewDecoderFS opens the named journal from fsys. Include
+// directives are resolved against the same fsys, relative
+// to the directory of the journal that includes them
+func NewDecoderFS(fsys fs.FS, name string) (*Decoder, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(file, name, fsys)
+	d.closer = file
+	return d, nil
+}
+
+func newDecoder(r io.Reader, name string, fsys fs.FS) *Decoder {
+	journal := &Journal{
+		Filepath:        name,
+		Decimal:         DefaultNumberFormat.Decimal,
+		DefaultCurrency: DefaultCurrency,
+		Alias:           make(map[string]string),
+		Includes:        make([]Journal, 0),
+	}
+	return &Decoder{
+		s: Scanner{
+			filename: name,
+			journal:  journal,
+			Scanner:  bufio.NewScanner(r),
+		},
+		journal: journal,
+		fsys:    fsys,
+	}
+}
+
+// WithFS resolves include directives against fsys instead
+// of the local filesystem
+func (d *Decoder) WithFS(fsys fs.FS) *Decoder {
+	d.fsys = fsys
+	return d
+}
+
+// Journal holds the state collected from directives so far,
+// it is only complete once Next has returned io.EOF
+func (d *Decoder) Journal() *Journal {
+	return d.journal
+}
+
+// Next returns the next balanced transaction in file order,
+// transactions from included files are returned in place of
+// the include directive.
+//
+// Errors are not fatal: the decoder skips over the offending
+// line and the caller may keep calling Next. A transaction that
+// does not balance is returned along with the error. Next returns
+// io.EOF once the journal and all its includes have been read
+func (d *Decoder) Next() (Transaction, error) {
+	for {
+		if d.inc != nil {
+			tx, err := d.inc.Next()
+			if err != io.EOF {
+				return tx, err
+			}
+			d.journal.Includes = append(d.journal.Includes, *d.inc.journal)
+			d.inc.Close()
+			d.inc = nil
+		}
+		if d.replay != nil {
+			tx, err, ok := d.replay.next()
+			if ok {
+				return tx, err
+			}
+			d.journal.Includes = append(d.journal.Includes, d.replay.journal)
+			d.replay = nil
+		}
+
+		if d.done {
+			return Transaction{}, io.EOF
+		}
+		if !d.s.Scan() {
+			d.done = true
+			d.journal.end = d.s.scanState
+			if err := d.s.Err(); err != nil {
+				return Transaction{}, d.s.wrap(err)
+			}
+			return Transaction{}, io.EOF
+		}
+
+		if d.s.ParseNote(d.s.Bytes()) == nil {
+			continue
+		}
+
+		// read before trimming, the metadata may be comments
+		if d.s.commodity != "" && d.s.parseCommodityMeta(d.s.Bytes()) {
+			continue
+		}
+
+		// trim comments, skip empty lines
+		line, empty, _ := tidy(d.s.Bytes())
+		if empty {
+			continue
+		}
+
+		// the payee and tag sub-directives are not used
+		if d.s.subdirectives {
+			if unicode.IsSpace(rune(line[0])) {
+				continue
+			}
+			d.s.subdirectives = false
+		}
+
+		// check for transaction (common case)
+		tx, err := d.s.ParseTransaction(line)
+		if err == nil {
+			return tx, balanceTransaction(&tx)
+		} else if err != ErrNoMatch {
+			return Transaction{}, err
+		}
+
+		// check for directives (rare case)
+		err = d.parseDirective(line)
+		if err == nil {
+			continue
+		} else if err != ErrNoMatch {
+			return Transaction{}, err
+		}
+
+		return Transaction{}, d.s.wrap(fmt.Errorf("skiped line: '%s'", line))
+	}
+}
+
+// All is the range-over-func form of Next, it stops at io.EOF
+func (d *Decoder) All() iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		for {
+			tx, err := d.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(tx, err) {
+				return
+			}
+		}
+	}
+}
+
+// Close releases any file opened by the decoder, including
+// the files of includes that were not read to the end
+func (d *Decoder) Close() error {
+	if d.inc != nil {
+		d.inc.Close()
+		d.inc = nil
+	}
+	if d.closer != nil {
+		err := d.closer.Close()
+		d.closer = nil
+		return err
+	}
+	return nil
+}
+
+// include directives are handled by the decoder since they
+// open a nested decoder, the rest are left to the scanner
+func (d *Decoder) parseDirective(line []byte) error {
+	name, ok := matchInclude(line)
+	if !ok {
+		return d.s.ParseDirective(line)
+	}
+	if p, ok := d.prefetched[d.s.row]; ok {
+		<-p.done
+		if p.openErr != nil {
+			d.journal.Includes = append(d.journal.Includes, Journal{Filepath: p.path})
+			return d.s.wrap(p.openErr)
+		}
+		d.replay = p
+		return nil
+	}
+	inc, err := d.openInclude(name)
+	if err != nil {
+		// kept without content, so that it can be watched for
+		d.journal.Includes = append(d.journal.Includes, Journal{Filepath: d.includePath(name)})
+		return d.s.wrap(err)
+	}
+	d.inc = inc
+	return nil
+}
+
+func matchInclude(line []byte) (string, bool) {
+	if !bytes.HasPrefix(line, []byte("include")) {
+		return "", false
+	}
+	return strings.TrimSpace(string(line[len("include"):])), true
+}
+
+// include path should be relative to the current journal path
+func (d *Decoder) includePath(name string) string {
+	if d.fsys == nil {
+		return ParsePath(filepath.Dir(d.journal.Filepath), name)
+	}
+	return path.Join(path.Dir(d.journal.Filepath), name)
+}
+
+func (d *Decoder) openInclude(name string) (*Decoder, error) {
+	incfile := d.includePath(name)
+	if d.fsys == nil {
+		file, err := os.Open(incfile)
+		if err != nil {
+			return nil, err
+		}
+		inc := newDecoder(file, incfile, nil)
+		inc.closer = file
+		return inc, nil
+	}
+
+	if !fs.ValidPath(incfile) {
+		return nil, fmt.Errorf("invalid include path '%s'", name)
+	}
+	return NewDecoderFS(d.fsys, incfile)
+}
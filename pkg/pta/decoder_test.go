@@ -0,0 +1,265 @@
+package pta
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDecoderMatchesParseJournal(t *testing.T) {
+	file := "./test/test.journal"
+	_, expected, err := ParseJournal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d := NewDecoder(f)
+	// includes are relative to the journal
+	d.Journal().Filepath = file
+	defer d.Close()
+
+	var got []Transaction
+	for tx, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tx)
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d transactions, got %d", len(expected), len(got))
+	}
+	for i := range got {
+		if WriteTransaction(got[i]) != WriteTransaction(expected[i]) {
+			t.Errorf("transaction %d does not match:\n%s\n%s", i,
+				WriteTransaction(got[i]), WriteTransaction(expected[i]))
+		}
+	}
+	if len(d.Journal().Includes) != 1 {
+		t.Errorf("expected 1 include, got %d", len(d.Journal().Includes))
+	}
+}
+
+func TestDecoderFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"books/main.journal": {Data: []byte(
+			"2023/11/01 first\n" +
+				"    assets:cash    $10\n" +
+				"    income:gift\n" +
+				"\n" +
+				"include sub/inc.journal\n" +
+				"\n" +
+				"2023/11/03 third\n" +
+				"    assets:cash    $30\n" +
+				"    income:gift\n")},
+		"books/sub/inc.journal": {Data: []byte(
+			"2023/11/02 second\n" +
+				"    assets:cash    $20\n" +
+				"    income:gift\n")},
+	}
+
+	d, err := NewDecoderFS(fsys, "books/main.journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	var descs []string
+	for {
+		tx, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		descs = append(descs, tx.Description)
+	}
+
+	if strings.Join(descs, ",") != "first,second,third" {
+		t.Errorf("unexpected transaction order: %v", descs)
+	}
+	inc := d.Journal().Includes
+	if len(inc) != 1 || inc[0].Filepath != "books/sub/inc.journal" {
+		t.Errorf("unexpected includes: %+v", inc)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	in := "bad format line\n" +
+		"\n" +
+		"2023/11/20 unbalanced\n" +
+		"    assets:cash    $10\n" +
+		"    income:gift  - $5\n" +
+		"\n" +
+		"include missing.journal\n" +
+		"\n" +
+		"2023/11/21 ok\n" +
+		"    assets:cash    $10\n" +
+		"    income:gift\n"
+
+	d := NewDecoder(strings.NewReader(in)).WithFS(fstest.MapFS{})
+
+	var txs []Transaction
+	var errs []error
+	for tx, err := range d.All() {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if tx.Date != NotDate {
+			txs = append(txs, tx)
+		}
+	}
+
+	// skipped line, unbalanced tx, missing include
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %d: %v", len(errs), errs)
+	}
+	if len(errs) > 0 && !strings.Contains(errs[0].Error(), "skiped line") {
+		t.Errorf("expected skipped line error, got: %v", errs[0])
+	}
+	// the unbalanced transaction is still returned
+	if len(txs) != 2 {
+		t.Errorf("expected 2 transactions, got %d", len(txs))
+	}
+	// the missing include is kept, so it can be watched
+	if inc := d.Journal().Includes; len(inc) != 1 || inc[0].Filepath != "missing.journal" {
+		t.Errorf("expected the missing include, got %+v", inc)
+	}
+}
+
+func TestDecoderStopEarly(t *testing.T) {
+	in := strings.Repeat("2023/11/20 tx\n    assets:cash    $10\n    income:gift\n\n", 10)
+	d := NewDecoder(strings.NewReader(in))
+
+	count := 0
+	for range d.All() {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected to stop after 3 transactions, got %d", count)
+	}
+
+	// the decoder can still be resumed
+	tx, err := d.Next()
+	if err != nil || tx.Description != "tx" {
+		t.Errorf("expected to resume decoding, got: %+v, %v", tx, err)
+	}
+}
+
+func TestDecoderNotes(t *testing.T) {
+	in := "; opening notes\n" +
+		"# over two lines\n" +
+		"\n" +
+		"2024/01/02 first\n" +
+		"    assets:cash  $10\n" +
+		"; not a note, in the transaction\n" +
+		"    income\n" +
+		"\n" +
+		"% ledger comment\n" +
+		"| another\n" +
+		"* org heading\n" +
+		"\n" +
+		"comment\n" +
+		"a block\n" +
+		"  2024/01/03 commented out\n" +
+		"end comment\n"
+
+	d := NewDecoder(strings.NewReader(in))
+	count := 0
+	for _, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected 1 transaction, got %d", count)
+	}
+
+	expected := []Note{
+		{Row: 1, Text: "opening notes\nover two lines"},
+		{Row: 9, Text: "ledger comment\nanother\norg heading"},
+		{Row: 13, Text: "a block\n  2024/01/03 commented out"},
+	}
+	notes := d.Journal().Notes
+	if len(notes) != len(expected) {
+		t.Fatalf("expected %d notes, got %+v", len(expected), notes)
+	}
+	for i := range expected {
+		if notes[i] != expected[i] {
+			t.Errorf("expected note %+v, got %+v", expected[i], notes[i])
+		}
+	}
+}
+
+func TestDecoderSettings(t *testing.T) {
+	in := "fire:withdrawal-rate 3.5%\n" +
+		"fire:currency   CAD ; comment\n" +
+		"\n" +
+		"2024/01/02 first\n" +
+		"    assets:cash  $10\n" +
+		"    income\n" +
+		"\n" +
+		"fire:real-return\n"
+
+	d := NewDecoder(strings.NewReader(in))
+	var errs []error
+	for _, err := range d.All() {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing value of 'fire:real-return'") {
+		t.Errorf("expected a missing value error, got %v", errs)
+	}
+	for key, expected := range map[string]string{"fire:withdrawal-rate": "3.5%", "fire:currency": "CAD"} {
+		if value, _ := d.Journal().Setting(key); value != expected {
+			t.Errorf("expected %s for %s, got '%s'", expected, key, value)
+		}
+	}
+}
+
+func TestDecoderCommodities(t *testing.T) {
+	in := "commodity VTI\n" +
+		"    ; class: us-equity\n" +
+		"    format 1.000 VTI\n" +
+		"commodity $1,000.00\n" +
+		"    class: cash\n" +
+		"\n" +
+		"2024/01/02 first\n" +
+		"    assets:cash  $10\n" +
+		"    income\n" +
+		"\n" +
+		"commodity BND ; no metadata\n"
+
+	d := NewDecoder(strings.NewReader(in))
+	for _, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	journal := d.Journal()
+	for code, expected := range map[string]string{"VTI": "us-equity", "USD": "cash"} {
+		if class, _ := journal.CommodityMeta(code, "class"); class != expected {
+			t.Errorf("expected %s for %s, got '%s'", expected, code, class)
+		}
+	}
+	if _, found := journal.Commodities["BND"]; !found {
+		t.Error("expected BND to be declared")
+	}
+	if _, found := journal.CommodityMeta("BND", "class"); found {
+		t.Error("expected BND without class")
+	}
+}
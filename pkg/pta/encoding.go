@@ -0,0 +1,226 @@
+package pta
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The JSON and CSV encodings are meant for other tools (pandas,
+// DuckDB, spreadsheets), the fields are always present so the
+// schema doesn't depend on the journal:
+//
+//	[{"date": "2024-01-15", "status": "cleared", "code": "", "description": "...",
+//	  "tags": [], "meta": {}, "postings": [{"account": "...", "amount": "-12.50",
+//	  "commodity": "USD", "unit_value": null, "assertion": null}]}]
+//
+// Dates are ISO dates and the amounts are strings, to keep
+// their exact decimal value
+
+type jsonTransaction struct {
+	Date        string            `json:"date"`
+	Status      string            `json:"status"` // cleared, pending or ""
+	Code        string            `json:"code"`
+	Description string            `json:"description"`
+	Tags        []string          `json:"tags"`
+	Meta        map[string]string `json:"meta"`
+	Postings    []jsonPosting     `json:"postings"`
+}
+
+type jsonPosting struct {
+	Account   string     `json:"account"`
+	Amount    string     `json:"amount"`
+	Commodity string     `json:"commodity"`
+	UnitValue *jsonValue `json:"unit_value"`
+	Assertion *jsonValue `json:"assertion"`
+}
+
+type jsonValue struct {
+	Amount    string `json:"amount"`
+	Commodity string `json:"commodity"`
+}
+
+// the columns of the CSV encoding, one row per posting
+var csvHeader = []string{
+	"txid", "date", "status", "code", "description", "account", "amount", "commodity",
+	"unit_value", "unit_commodity", "assertion", "assertion_commodity", "tags",
+}
+
+// EncodeJSON writes the transactions as a JSON array
+func EncodeJSON(w io.Writer, txs []Transaction) error {
+	out := make([]jsonTransaction, 0, len(txs))
+	for _, tx := range txs {
+		jtx := jsonTransaction{
+			Date:        tx.Date.Format(time.DateOnly),
+			Status:      txStatus(tx),
+			Code:        tx.Code,
+			Description: tx.Description,
+			Tags:        tx.Tags,
+			Meta:        tx.Meta,
+			Postings:    make([]jsonPosting, 0, len(tx.Postings)),
+		}
+		if jtx.Tags == nil {
+			jtx.Tags = []string{}
+		}
+		if jtx.Meta == nil {
+			jtx.Meta = map[string]string{}
+		}
+		for _, post := range tx.Postings {
+			jpost := jsonPosting{
+				Account:   post.Account,
+				Amount:    post.Amount.String(),
+				Commodity: post.Commodity.Code,
+			}
+			if !post.UnitValue.Decimal.IsZero() {
+				jpost.UnitValue = &jsonValue{post.UnitValue.Decimal.String(), post.UnitValue.Code}
+			}
+			if post.Assertion != nil {
+				jpost.Assertion = &jsonValue{post.Assertion.Decimal.String(), post.Assertion.Code}
+			}
+			jtx.Postings = append(jtx.Postings, jpost)
+		}
+		out = append(out, jtx)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// DecodeJSON reads transactions written by EncodeJSON, they
+// must balance like the journal transactions
+func DecodeJSON(r io.Reader) ([]Transaction, error) {
+	var in []jsonTransaction
+	err := json.NewDecoder(r).Decode(&in)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]Transaction, 0, len(in))
+	for i, jtx := range in {
+		tx, err := jtx.transaction()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %s", i+1, err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+func (jtx jsonTransaction) transaction() (tx Transaction, err error) {
+	tx.Date, err = time.Parse(time.DateOnly, jtx.Date)
+	if err != nil {
+		return tx, fmt.Errorf("bad date '%s'", jtx.Date)
+	}
+	switch jtx.Status {
+	case "cleared":
+		tx.Cleared = true
+	case "pending":
+		tx.Pending = true
+	case "":
+	default:
+		return tx, fmt.Errorf("unknown status '%s'", jtx.Status)
+	}
+	tx.Code = jtx.Code
+	tx.Description = jtx.Description
+	tx.Tags = jtx.Tags
+	if len(jtx.Meta) > 0 {
+		tx.Meta = jtx.Meta
+	}
+
+	if len(jtx.Postings) == 0 {
+		return tx, fmt.Errorf("missing postings")
+	}
+	for _, jpost := range jtx.Postings {
+		post := Posting{Account: jpost.Account}
+		if post.Account == "" {
+			return tx, fmt.Errorf("missing posting account")
+		}
+		post.Amount, post.Commodity, err = jsonAmount(jpost.Amount, jpost.Commodity)
+		if err != nil {
+			return
+		}
+		if jpost.UnitValue != nil {
+			post.UnitValue.Decimal, post.UnitValue.Commodity, err = jsonAmount(jpost.UnitValue.Amount, jpost.UnitValue.Commodity)
+			if err != nil {
+				return
+			}
+		}
+		if jpost.Assertion != nil {
+			post.Assertion = &Value{}
+			post.Assertion.Decimal, post.Assertion.Commodity, err = jsonAmount(jpost.Assertion.Amount, jpost.Assertion.Commodity)
+			if err != nil {
+				return
+			}
+		}
+		tx.Postings = append(tx.Postings, post)
+	}
+	err = balanceTransaction(&tx)
+	return
+}
+
+func jsonAmount(amount, code string) (decimal.Decimal, Commodity, error) {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return d, Commodity{}, fmt.Errorf("bad amount '%s'", amount)
+	}
+	if code == "" {
+		return d, Commodity{}, fmt.Errorf("missing commodity of '%s'", amount)
+	}
+	return d, CommodityFromCode(code), nil
+}
+
+// EncodeCSV writes a row per posting, the transaction columns
+// are repeated on each of its postings, and txid groups them
+func EncodeCSV(w io.Writer, txs []Transaction) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for i, tx := range txs {
+		for _, post := range tx.Postings {
+			var unitValue, unitCode, assertion, assertionCode string
+			if !post.UnitValue.Decimal.IsZero() {
+				unitValue, unitCode = post.UnitValue.Decimal.String(), post.UnitValue.Code
+			}
+			if post.Assertion != nil {
+				assertion, assertionCode = post.Assertion.Decimal.String(), post.Assertion.Code
+			}
+			err = cw.Write([]string{
+				strconv.Itoa(i + 1),
+				tx.Date.Format(time.DateOnly),
+				txStatus(tx),
+				tx.Code,
+				tx.Description,
+				post.Account,
+				post.Amount.String(),
+				post.Commodity.Code,
+				unitValue, unitCode,
+				assertion, assertionCode,
+				strings.Join(tx.Tags, " "),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func txStatus(tx Transaction) string {
+	switch {
+	case tx.Cleared:
+		return "cleared"
+	case tx.Pending:
+		return "pending"
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package pta
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+const encodingJournal = `
+2024/01/02 * (101) ACME payroll #work
+	; memo: january
+	assets:bank:checking  $2,500.00
+	income:salary
+
+2024/01/10 ! Buy VTI
+	assets:brokerage      4 VTI @ $240.00
+	equity:trading       -4 VTI
+	equity:trading        $960.00
+	assets:bank:checking  -$960.00 = $1,540.00
+`
+
+func decodeAll(t *testing.T, in string) []Transaction {
+	var txs []Transaction
+	for tx, err := range NewDecoder(strings.NewReader(in)).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func TestEncodeJSON(t *testing.T) {
+	txs := decodeAll(t, encodingJournal)
+
+	var buf bytes.Buffer
+	err := EncodeJSON(&buf, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, field := range []string{
+		`"date": "2024-01-02"`,
+		`"status": "cleared"`,
+		`"code": "101"`,
+		`"tags": [` + "\n" + `      "work"`,
+		`"memo": "january"`,
+		`"amount": "-2500"`,
+		`"unit_value": {` + "\n" + `          "amount": "240",` + "\n" + `          "commodity": "USD"`,
+		`"assertion": null`,
+		`"meta": {}`,
+	} {
+		if !strings.Contains(out, field) {
+			t.Errorf("expected %s in:\n%s", field, out)
+		}
+	}
+
+	// and back
+	decoded, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(txs) {
+		t.Fatalf("expected %d transactions, got %d", len(txs), len(decoded))
+	}
+	for i := range txs {
+		if WriteTransaction(decoded[i]) != WriteTransaction(txs[i]) {
+			t.Errorf("expected\n%s\ngot\n%s", WriteTransaction(txs[i]), WriteTransaction(decoded[i]))
+		}
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{`[{"date": "2024/01/02", "postings": []}]`, "bad date"},
+		{`[{"date": "2024-01-02", "postings": []}]`, "missing postings"},
+		{`[{"date": "2024-01-02", "status": "reconciled", "postings": []}]`, "unknown status"},
+		{`[{"date": "2024-01-02", "postings": [{"account": "a", "amount": "1,00", "commodity": "USD"}]}]`, "bad amount"},
+		{`[{"date": "2024-01-02", "postings": [{"account": "a", "amount": "1", "commodity": ""}]}]`, "missing commodity"},
+		{`[{"date": "2024-01-02", "postings": [
+			{"account": "a", "amount": "1", "commodity": "USD"},
+			{"account": "b", "amount": "-2", "commodity": "USD"}]}]`, "not balanced"},
+	}
+	for _, c := range cases {
+		_, err := DecodeJSON(strings.NewReader(c.in))
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("expected an error with %q for %s, got: %v", c.expected, c.in, err)
+		}
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	txs := decodeAll(t, encodingJournal)
+
+	var buf bytes.Buffer
+	err := EncodeCSV(&buf, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 7 {
+		t.Fatalf("expected a header and 6 postings, got %d rows", len(records))
+	}
+	expected := [][]string{
+		csvHeader,
+		{"1", "2024-01-02", "cleared", "101", "ACME payroll #work", "assets:bank:checking", "2500", "USD", "", "", "", "", "work"},
+		{"1", "2024-01-02", "cleared", "101", "ACME payroll #work", "income:salary", "-2500", "USD", "", "", "", "", "work"},
+		{"2", "2024-01-10", "pending", "", "Buy VTI", "assets:brokerage", "4", "VTI", "240", "USD", "", "", ""},
+	}
+	for i, exp := range expected {
+		if strings.Join(records[i], "|") != strings.Join(exp, "|") {
+			t.Errorf("row %d: expected %v, got %v", i, exp, records[i])
+		}
+	}
+	if records[6][10] != "1540" || records[6][11] != "USD" {
+		t.Errorf("expected the balance assertion, got %v", records[6])
+	}
+}
@@ -0,0 +1,221 @@
+package pta
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Envelope budgeting: the income is assigned to envelopes with
+// balanced virtual postings to 'budget:' accounts, the expenses
+// draw down the envelope of the same name:
+//
+//	2024/01/01 Payroll
+//		assets:bank        $3,000.00
+//		income:salary
+//		[budget:food]        $400.00
+//		[budget:rent]      $1,500.00
+//		[budget]          -$1,900.00
+//
+// expenses:food and its sub-accounts draw from budget:food, unless
+// there is a more specific envelope (budget:food:dining). The
+// income not assigned yet is available to assign, the expenses
+// without an envelope are taken from it
+
+// EnvelopeLine is an envelope in a period, in one commodity
+type EnvelopeLine struct {
+	Envelope  string // without the 'budget:' prefix
+	Commodity Commodity
+	Assigned  decimal.Decimal // in the period, negative when moved out
+	Spent     decimal.Decimal // in the period
+	Balance   decimal.Decimal // at the end of the period
+}
+
+type EnvelopePeriod struct {
+	Start      time.Time
+	End        time.Time // included
+	Income     []Value   // in the period
+	Unbudgeted []Value   // expenses without an envelope, in the period
+	Available  []Value   // to assign, at the end of the period
+	Lines      []EnvelopeLine
+}
+
+func (l EnvelopeLine) Overspent() bool {
+	return l.Balance.IsNegative()
+}
+
+// EnvelopeReport returns the envelopes of each period, from the
+// first transaction to the last. The balances carry over from a
+// period to the next
+func EnvelopeReport(txs []Transaction, period Period) []EnvelopePeriod {
+	var first, last time.Time
+	var envelopes []string
+	for _, tx := range txs {
+		if first.IsZero() || tx.Date.Before(first) {
+			first = tx.Date
+		}
+		if tx.Date.After(last) {
+			last = tx.Date
+		}
+		for _, post := range tx.Postings {
+			if name, ok := envelopeName(post.Account); ok && !slices.Contains(envelopes, name) {
+				envelopes = append(envelopes, name)
+			}
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	slices.Sort(envelopes)
+
+	type key struct {
+		envelope string
+		code     string
+	}
+	commodities := make(map[string]Commodity)
+	balances := make(map[key]decimal.Decimal)
+	available := make(map[string]decimal.Decimal)
+
+	var report []EnvelopePeriod
+	for start := period.Start(first); !start.After(last); start = period.Next(start) {
+		end := period.End(start)
+		var within []Transaction
+		for _, tx := range txs {
+			if !tx.Date.Before(start) && !tx.Date.After(end) {
+				within = append(within, tx)
+			}
+		}
+
+		assigned := make(map[key]decimal.Decimal)
+		for _, tx := range within {
+			for _, post := range tx.Postings {
+				if name, ok := envelopeName(post.Account); ok {
+					k := key{name, post.Commodity.Code}
+					assigned[k] = assigned[k].Add(post.Amount)
+					commodities[post.Commodity.Code] = post.Commodity
+				}
+			}
+		}
+
+		statement := ComputeIncomeStatement(within)
+		income := make(map[string]decimal.Decimal)
+		for _, lots := range statement.revenue {
+			for _, lot := range lots {
+				income[lot.Commodity.Code] = income[lot.Commodity.Code].Add(lot.Amount)
+				commodities[lot.Commodity.Code] = lot.Commodity
+			}
+		}
+		spent := make(map[key]decimal.Decimal)
+		unbudgeted := make(map[string]decimal.Decimal)
+		for account, lots := range statement.expenses {
+			envelope := matchEnvelope(envelopes, account)
+			for _, lot := range lots {
+				commodities[lot.Commodity.Code] = lot.Commodity
+				if envelope == "" {
+					unbudgeted[lot.Commodity.Code] = unbudgeted[lot.Commodity.Code].Add(lot.Amount)
+					continue
+				}
+				k := key{envelope, lot.Commodity.Code}
+				spent[k] = spent[k].Add(lot.Amount)
+			}
+		}
+
+		for code, amount := range income {
+			available[code] = available[code].Add(amount)
+		}
+		for code, amount := range unbudgeted {
+			available[code] = available[code].Sub(amount)
+		}
+		for k, amount := range assigned {
+			available[k.code] = available[k.code].Sub(amount)
+		}
+
+		var keys []key
+		for _, m := range []map[key]decimal.Decimal{balances, assigned, spent} {
+			for k := range m {
+				if !slices.Contains(keys, k) {
+					keys = append(keys, k)
+				}
+			}
+		}
+		slices.SortFunc(keys, func(a, b key) int {
+			if c := strings.Compare(a.envelope, b.envelope); c != 0 {
+				return c
+			}
+			return strings.Compare(a.code, b.code)
+		})
+
+		p := EnvelopePeriod{
+			Start:      start,
+			End:        end,
+			Income:     sortedValues(income, commodities),
+			Unbudgeted: sortedValues(unbudgeted, commodities),
+			Available:  sortedValues(available, commodities),
+		}
+		for _, k := range keys {
+			balances[k] = balances[k].Add(assigned[k]).Sub(spent[k])
+			line := EnvelopeLine{
+				Envelope:  k.envelope,
+				Commodity: commodities[k.code],
+				Assigned:  assigned[k],
+				Spent:     spent[k],
+				Balance:   balances[k],
+			}
+			if line.Assigned.IsZero() && line.Spent.IsZero() && line.Balance.IsZero() {
+				continue
+			}
+			p.Lines = append(p.Lines, line)
+		}
+		report = append(report, p)
+	}
+	return report
+}
+
+// the envelope of a virtual posting: '[budget:food]' or '(budget:food)'
+func envelopeName(account string) (string, bool) {
+	if len(account) < 2 {
+		return "", false
+	}
+	switch account[0] {
+	case '[':
+		account = strings.TrimSuffix(account[1:], "]")
+	case '(':
+		account = strings.TrimSuffix(account[1:], ")")
+	default:
+		return "", false
+	}
+	name, found := strings.CutPrefix(account, "budget:")
+	return name, found && name != ""
+}
+
+// the most specific envelope of an expense account, expenses:food:dining
+// draws from food:dining, or else from food
+func matchEnvelope(envelopes []string, account string) string {
+	_, name, found := strings.Cut(account, ":")
+	if !found {
+		return ""
+	}
+	var match string
+	for _, envelope := range envelopes {
+		if (name == envelope || strings.HasPrefix(name, envelope+":")) && len(envelope) > len(match) {
+			match = envelope
+		}
+	}
+	return match
+}
+
+// the non-zero amounts, by code
+func sortedValues(amounts map[string]decimal.Decimal, commodities map[string]Commodity) []Value {
+	var values []Value
+	for code, amount := range amounts {
+		if !amount.IsZero() {
+			values = append(values, Value{Decimal: amount, Commodity: commodities[code]})
+		}
+	}
+	slices.SortFunc(values, func(a, b Value) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return values
+}
@@ -0,0 +1,91 @@
+package pta
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+const envelopeJournal = `
+2024/01/01 Payroll
+	assets:bank        $3,000.00
+	income:salary
+	[budget:food]        $400.00
+	[budget:rent]      $1,500.00
+	[budget]          -$1,900.00
+
+2024/01/05 Groceries
+	expenses:food:groceries  $120.00
+	assets:bank
+
+2024/01/10 Rent
+	expenses:rent  $1,500.00
+	assets:bank
+
+2024/01/20 Movies
+	expenses:fun  $30.00
+	assets:bank
+
+2024/02/01 Move to dining
+	[budget:food]         -$50.00
+	[budget:food:dining]   $50.00
+
+2024/02/03 Dinner
+	expenses:food:dining  $80.00
+	assets:bank
+`
+
+func TestEnvelopeReport(t *testing.T) {
+	_, txs := parseTestJournal(t, envelopeJournal)
+	report := EnvelopeReport(txs, Monthly)
+	if len(report) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(report))
+	}
+
+	type line struct {
+		envelope                 string
+		assigned, spent, balance int64
+	}
+	check := func(p EnvelopePeriod, available int64, expected []line) {
+		t.Helper()
+		if len(p.Available) != 1 || !p.Available[0].Decimal.Equal(decimal.New(available, 0)) {
+			t.Errorf("expected %d available, got %v", available, p.Available)
+		}
+		if len(p.Lines) != len(expected) {
+			t.Fatalf("expected %d envelopes, got %+v", len(expected), p.Lines)
+		}
+		for i, e := range expected {
+			l := p.Lines[i]
+			if l.Envelope != e.envelope || !l.Assigned.Equal(decimal.New(e.assigned, 0)) ||
+				!l.Spent.Equal(decimal.New(e.spent, 0)) || !l.Balance.Equal(decimal.New(e.balance, 0)) {
+				t.Errorf("expected %+v, got %+v", e, l)
+			}
+		}
+	}
+
+	// the movies are not budgeted
+	check(report[0], 1070, []line{{"food", 400, 120, 280}, {"rent", 1500, 1500, 0}})
+	if len(report[0].Unbudgeted) != 1 || !report[0].Unbudgeted[0].Decimal.Equal(decimal.New(30, 0)) {
+		t.Errorf("expected 30 unbudgeted, got %v", report[0].Unbudgeted)
+	}
+	// dining has its own envelope, overspent
+	check(report[1], 1070, []line{{"food", -50, 0, 230}, {"food:dining", 50, 80, -30}})
+	if !report[1].Lines[1].Overspent() {
+		t.Error("expected dining overspent")
+	}
+}
+
+func TestMatchEnvelope(t *testing.T) {
+	envelopes := []string{"food", "food:dining", "rent"}
+	for account, expected := range map[string]string{
+		"expenses:food":            "food",
+		"expenses:food:groceries":  "food",
+		"expenses:food:dining:bar": "food:dining",
+		"expenses:foodtruck":       "",
+		"expenses":                 "",
+	} {
+		if envelope := matchEnvelope(envelopes, account); envelope != expected {
+			t.Errorf("expected '%s' for %s, got '%s'", expected, account, envelope)
+		}
+	}
+}
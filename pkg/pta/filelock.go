@@ -0,0 +1,57 @@
+package pta
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrConflict is returned when appending to a journal file that
+// changed since it was parsed (by another tab, or an editor)
+var ErrConflict = fmt.Errorf("journal file changed since it was read")
+
+// writers within this process are serialized with a mutex per file,
+// the advisory file lock (flock) covers other processes
+type fileMutexes struct {
+	kv map[string]*sync.Mutex
+	sync.Mutex
+}
+
+var fileLocks = fileMutexes{
+	kv: make(map[string]*sync.Mutex),
+}
+
+func (m *fileMutexes) get(path string) *sync.Mutex {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	m.Lock()
+	defer m.Unlock()
+	mu, found := m.kv[path]
+	if !found {
+		mu = &sync.Mutex{}
+		m.kv[path] = mu
+	}
+	return mu
+}
+
+// locks the file for writing, in process and across processes
+func lockFile(f *os.File) (unlock func(), err error) {
+	mu := fileLocks.get(f.Name())
+	mu.Lock()
+	err = flock(f)
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		funlock(f)
+		mu.Unlock()
+	}, nil
+}
+
+func hashVersion(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
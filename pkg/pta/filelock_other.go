@@ -0,0 +1,14 @@
+//go:build !unix
+
+package pta
+
+import "os"
+
+// no advisory locks, only the in process mutex applies
+func flock(f *os.File) error {
+	return nil
+}
+
+func funlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package pta
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// NetWorthPoint is the balance sheet at the end of a period, in
+// the target commodity. Liabilities are what is owed, positive
+type NetWorthPoint struct {
+	Date        time.Time
+	Assets      decimal.Decimal
+	Liabilities decimal.Decimal
+	NetWorth    decimal.Decimal
+	Missing     []string // commodities without a price, left out
+}
+
+// NetWorthSeries values the starting balance (OpeningBalance) and
+// the assets and liabilities at the end of each period, from the
+// first transaction to the last, with the prices known at that date
+func NetWorthSeries(start BalanceStatement, txs []Transaction, prices []Price, period Period, target Commodity) []NetWorthPoint {
+	sorted := append(start.transactions(), txs...)
+	if len(sorted) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(sorted, func(a, b Transaction) int {
+		return a.Date.Compare(b.Date)
+	})
+	history := NewPriceHistory(prices)
+
+	// balances by commodity
+	assets := make(map[string]decimal.Decimal)
+	liabilities := make(map[string]decimal.Decimal)
+	var codes []string
+
+	var series []NetWorthPoint
+	last := sorted[len(sorted)-1].Date
+	i := 0
+	for end := period.End(sorted[0].Date); ; end = period.End(end.AddDate(0, 0, 1)) {
+		for ; i < len(sorted) && !sorted[i].Date.After(end); i++ {
+			for _, post := range sorted[i].Postings {
+				var balances map[string]decimal.Decimal
+				switch {
+				case strings.Contains(post.Account, "asset"):
+					balances = assets
+				case strings.Contains(post.Account, "liability") || strings.Contains(post.Account, "liabilities"):
+					balances = liabilities
+				default:
+					continue
+				}
+				code := post.Commodity.Code
+				if !slices.Contains(codes, code) {
+					codes = append(codes, code)
+				}
+				balances[code] = balances[code].Add(post.Amount)
+			}
+		}
+
+		point := NetWorthPoint{Date: end}
+		for _, code := range codes {
+			if assets[code].IsZero() && liabilities[code].IsZero() {
+				continue
+			}
+			asset, _, assetFound := history.Convert(assets[code], code, target.Code, end)
+			liability, _, liabilityFound := history.Convert(liabilities[code], code, target.Code, end)
+			if !assetFound || !liabilityFound {
+				point.Missing = append(point.Missing, code)
+				continue
+			}
+			point.Assets = point.Assets.Add(asset)
+			point.Liabilities = point.Liabilities.Sub(liability)
+		}
+		point.NetWorth = point.Assets.Sub(point.Liabilities)
+		series = append(series, point)
+
+		if !end.Before(last) {
+			return series
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package pta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNetWorthSeries(t *testing.T) {
+	journal, txs := parseTestJournal(t, valuationJournal+`
+2024/03/05 Groceries
+	expenses:food     $100.00
+	liabilities:visa
+`)
+	series := NetWorthSeries(BalanceStatement{}, txs, journal.AllPrices(), Monthly, CommodityFromCode("CAD"))
+
+	expected := []struct {
+		date                          string
+		assets, liabilities, netWorth string
+	}{
+		// the prices of january
+		{"2024-01-31", "2595", "0", "2595"},
+		// the prices of february, no transactions
+		{"2024-02-29", "2749", "0", "2749"},
+		{"2024-03-31", "2749", "135", "2614"},
+	}
+	if len(series) != len(expected) {
+		t.Fatalf("expected %d points, got %+v", len(expected), series)
+	}
+	for i, exp := range expected {
+		point := series[i]
+		if point.Date.Format(time.DateOnly) != exp.date ||
+			!point.Assets.Equal(decimal.RequireFromString(exp.assets)) ||
+			!point.Liabilities.Equal(decimal.RequireFromString(exp.liabilities)) ||
+			!point.NetWorth.Equal(decimal.RequireFromString(exp.netWorth)) {
+			t.Errorf("expected %+v, got %s %s %s %s", exp, point.Date.Format(time.DateOnly),
+				point.Assets, point.Liabilities, point.NetWorth)
+		}
+		if len(point.Missing) != 1 || point.Missing[0] != "XAU" {
+			t.Errorf("expected the gold without a price, got %v", point.Missing)
+		}
+	}
+
+	yearly := NetWorthSeries(BalanceStatement{}, txs, journal.AllPrices(), Yearly, CommodityFromCode("CAD"))
+	if len(yearly) != 1 || yearly[0].Date.Format(time.DateOnly) != "2024-12-31" {
+		t.Errorf("expected a point at the end of the year, got %+v", yearly)
+	}
+}
+
+func TestNetWorthSeriesMissingLiability(t *testing.T) {
+	journal, txs := parseTestJournal(t, `
+2024/01/01 borrowed silver
+	expenses:jewelry     1 XAG
+	liabilities:silver  -1 XAG
+`)
+	series := NetWorthSeries(BalanceStatement{}, txs, journal.AllPrices(), Monthly, DefaultCurrency)
+	if len(series) != 1 {
+		t.Fatalf("expected 1 point, got %+v", series)
+	}
+	if point := series[0]; len(point.Missing) != 1 || point.Missing[0] != "XAG" || !point.Liabilities.IsZero() {
+		t.Errorf("expected the silver owed without a price, got %+v", point)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	date := time.Date(2024, 8, 14, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		period     Period
+		start, end string
+	}{
+		{Monthly, "2024-08-01", "2024-08-31"},
+		{Quarterly, "2024-07-01", "2024-09-30"},
+		{Yearly, "2024-01-01", "2024-12-31"},
+	} {
+		if start := c.period.Start(date).Format(time.DateOnly); start != c.start {
+			t.Errorf("%s: expected to start on %s, got %s", c.period, c.start, start)
+		}
+		if end := c.period.End(date).Format(time.DateOnly); end != c.end {
+			t.Errorf("%s: expected to end on %s, got %s", c.period, c.end, end)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package pta
+
+// AllNotes returns the notes of the journal, followed by
+// the notes of its includes
+func (j Journal) AllNotes() []Note {
+	notes := append([]Note{}, j.Notes...)
+	for _, inc := range j.Includes {
+		notes = append(notes, inc.AllNotes()...)
+	}
+	return notes
+}
+
+// Setting returns the value of a 'fire:' setting, the journal
+// settings take precedence over the ones of its includes
+func (j Journal) Setting(key string) (string, bool) {
+	if value, found := j.Settings[key]; found {
+		return value, true
+	}
+	for _, inc := range j.Includes {
+		if value, found := inc.Setting(key); found {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// CommodityMeta returns the metadata of a 'commodity' directive, the
+// journal directives take precedence over the ones of its includes
+func (j Journal) CommodityMeta(code, key string) (string, bool) {
+	if value, found := j.Commodities[code][key]; found {
+		return value, true
+	}
+	for _, inc := range j.Includes {
+		if value, found := inc.CommodityMeta(code, key); found {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -226,3 +226,11 @@ func BenchmarkParser(b *testing.B) {
 		_, _, _ = ParseJournal(file)
 	}
 }
+
+// 32029581 ns/op	 8909572 B/op	  155093 allocs/op
+func BenchmarkParserLarge(b *testing.B) {
+	file := "./test/bench1.journal"
+	for i := 0; i < b.N; i++ {
+		_, _, _ = ParseJournal(file)
+	}
+}
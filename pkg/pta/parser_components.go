@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -24,6 +26,22 @@ type Scanner struct {
 	filename string
 	row      int
 	col      int
+	scanState
+
+	// the indented lines following a payee or tag directive
+	subdirectives bool
+
+	// the code of the commodity directive whose metadata follows
+	commodity string
+
+	// the row of the last comment line, to join the notes
+	noteRow int
+}
+
+// set by directives, for the rest of the file
+type scanState struct {
+	year     int      // 'year 2024'
+	accounts []string // 'apply account' prefixes, nested
 }
 
 func (s *Scanner) Scan() bool {
@@ -48,7 +66,13 @@ func isDigit(r byte) bool {
 	return unicode.IsDigit(rune(r))
 }
 
+// ledger comments, only at the start of the line
+const lineComments = "#%|*"
+
 func tidy(line []byte) (retLine []byte, empty, hadComment bool) {
+	if len(line) > 0 && strings.IndexByte(lineComments, line[0]) != -1 {
+		return line[:0], true, true
+	}
 	if i := bytes.IndexByte(line, START_OF_COMMENT); i != -1 {
 		line = line[:i]
 		hadComment = true
@@ -81,45 +105,90 @@ func fastNewDecimal(tok []byte, nfractional int) decimal.Decimal {
 }
 
 func matchDate(tok []byte) bool {
-	return len(tok) >= 10 &&
-		isDigit(tok[0]) && isDigit(tok[1]) && isDigit(tok[2]) && isDigit(tok[3]) && // year
-		!isDigit(tok[4]) && // seperator
-		isDigit(tok[5]) && isDigit(tok[6]) && // month
-		!isDigit(tok[7]) && // seperator
-		isDigit(tok[8]) && isDigit(tok[9]) // day
+	_, _, _, _, ok := splitDate(tok)
+	return ok
 }
 
-// Date must have the format: YYYY/MM/DD, but it can use any not digit separator
-// The date is the first token in a transaction line. Failing to match the date
-// format simply means the line does not belong to a transaction, not an error
+// the date fields at the start of tok, and the length of the date.
+// The year is -1 when omitted
+func splitDate(tok []byte) (year, month, day, n int, ok bool) {
+	var fields, widths [3]int
+	count := 0
+	for count < 3 {
+		start := n
+		value := 0
+		for n < len(tok) && isDigit(tok[n]) && n-start < 4 {
+			value = 10*value + int(tok[n]-'0')
+			n++
+		}
+		if n == start || (n < len(tok) && isDigit(tok[n])) {
+			return
+		}
+		fields[count], widths[count] = value, n-start
+		count++
+		if count == 3 || n == len(tok) || unicode.IsSpace(rune(tok[n])) {
+			break
+		}
+		n++ // separator
+	}
+
+	switch {
+	case count == 3 && widths[0] == 4 && widths[1] <= 2 && widths[2] <= 2:
+		return fields[0], fields[1], fields[2], n, true
+	case count == 2 && widths[0] <= 2 && widths[1] <= 2:
+		return -1, fields[0], fields[1], n, true
+	}
+	return 0, 0, 0, 0, false
+}
+
+// Date has the format: YYYY/MM/DD, but it can use any not digit separator,
+// and the month and day can be a single digit: 2024/1/5. After a year
+// directive the year can be omitted: 1/5. The date is the first token in
+// a transaction line. Failing to match the date format simply means the
+// line does not belong to a transaction, not an error
 func (s *Scanner) ParseDate(tok []byte) (out time.Time, tail []byte, err error) {
-	if !matchDate(tok) {
+	year, month, day, n, ok := splitDate(tok)
+	if !ok {
 		return NotDate, tok, ErrNoMatch
 	}
-	if len(tok) > 10 { // must be followed by space, tab, or newline
-		if !unicode.IsSpace(rune(tok[10])) {
-			s.advance(tok, 10)
+	if len(tok) > n { // must be followed by space, tab, or newline
+		if !unicode.IsSpace(rune(tok[n])) {
+			s.advance(tok, n)
 			return NotDate, []byte{}, s.wrap(fmt.Errorf("date must be followed by space or newline"))
 		}
 	}
-	tok[4] = '/'
-	tok[7] = '/'
-	out, err = time.Parse("2006/01/02", string(tok[:10]))
-	if err != nil {
-		return NotDate, []byte{}, s.wrap(err)
+	if year == -1 {
+		if s.year == 0 {
+			return NotDate, []byte{}, s.wrap(fmt.Errorf("date without year '%s' needs a year directive", tok[:n]))
+		}
+		year = s.year
 	}
-	_, tail = s.advance(tok, 10)
+	out = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes the out of range days and months
+	if out.Month() != time.Month(month) || out.Day() != day {
+		return NotDate, []byte{}, s.wrap(fmt.Errorf("invalid date '%s'", tok[:n]))
+	}
+	_, tail = s.advance(tok, n)
 	return
 }
 
 // optional: '!' means the transaction is pending
 func (s *Scanner) ParseTxPending(tok []byte) (out bool, tail []byte, err error) {
-	if len(tok) > 0 && tok[0] == '!' {
+	return s.parseTxFlag(tok, '!')
+}
+
+// optional: '*' means the transaction is cleared
+func (s *Scanner) ParseTxCleared(tok []byte) (out bool, tail []byte, err error) {
+	return s.parseTxFlag(tok, '*')
+}
+
+func (s *Scanner) parseTxFlag(tok []byte, flag byte) (out bool, tail []byte, err error) {
+	if len(tok) > 0 && tok[0] == flag {
 		if len(tok) > 1 {
 			// must be followed by space, tab, or newline
 			if !unicode.IsSpace(rune(tok[1])) {
 				s.advance(tok, 1)
-				return false, []byte{}, s.wrap(fmt.Errorf("'!' must be followed by space or newline"))
+				return false, []byte{}, s.wrap(fmt.Errorf("'%c' must be followed by space or newline", flag))
 			}
 		}
 		out = true
@@ -416,7 +485,7 @@ func (s *Scanner) ParseCommodity(tok []byte) (amount decimal.Decimal, com Commod
 			return
 		}
 	} else {
-		com = commodityFromCode(com.Code)
+		com = CommodityFromCode(com.Code)
 	}
 
 	return
@@ -465,7 +534,7 @@ func (s *Scanner) ParseValue(count decimal.Decimal, tok []byte) (value Value, ta
 			return
 		}
 	} else {
-		value.Commodity = commodityFromCode(value.Code)
+		value.Commodity = CommodityFromCode(value.Code)
 		if value.Type != CURRENCY {
 			err = s.wrap(fmt.Errorf("unknown currency code: '%s'", value.Code))
 			return
@@ -478,3 +547,248 @@ func (s *Scanner) ParseValue(count decimal.Decimal, tok []byte) (value Value, ta
 
 	return
 }
+
+// the balance of the account (for the commodity) after the posting,
+// but with the posting amount optional: '= $100' or '$20 = $100'
+func (s *Scanner) ParseAssertion(tok []byte) (out *Value, err error) {
+	if len(tok) == 0 {
+		return nil, s.wrap(fmt.Errorf("missing balance assertion amount"))
+	}
+	var neg bool
+	neg, tok, err = s.ParsePostNeg(tok)
+	if err != nil {
+		return
+	}
+	out = &Value{}
+	out.Decimal, out.Commodity, tok, err = s.ParseCommodity(tok)
+	if err != nil {
+		return nil, err
+	}
+	if neg {
+		out.Decimal = out.Decimal.Neg()
+	}
+	if len(tok) > 0 {
+		return nil, s.wrap(fmt.Errorf("unexpected tokens after balance assertion: '%s'", tok))
+	}
+	return
+}
+
+// the market price of a commodity at a date: 'P 2024/01/15 VTI $245.10'
+func (s *Scanner) ParsePrice(line []byte) (out Price, err error) {
+	_, tok := s.advance(line, 1)
+	out.Date, tok, err = s.ParseDate(tok)
+	if err == ErrNoMatch {
+		return out, s.wrap(fmt.Errorf("price must be followed by a date"))
+	}
+	if err != nil {
+		return
+	}
+
+	end := bytes.IndexFunc(tok, unicode.IsSpace)
+	if end == -1 {
+		return out, s.wrap(fmt.Errorf("missing price of '%s'", tok))
+	}
+	var code []byte
+	code, tok = s.advance(tok, end)
+	out.Code = string(code)
+
+	var neg bool
+	neg, tok, err = s.ParsePostNeg(tok)
+	if err != nil {
+		return
+	}
+	out.Decimal, out.Commodity, tok, err = s.ParseCommodity(tok)
+	if err != nil {
+		return
+	}
+	if neg {
+		out.Decimal = out.Decimal.Neg()
+	}
+	if len(tok) > 0 {
+		return out, s.wrap(fmt.Errorf("unexpected tokens after price: '%s'", tok))
+	}
+	return
+}
+
+// 'year 2024', 'Y 2024' or 'Y2024'
+func matchYear(line []byte) ([]byte, bool) {
+	switch {
+	case len(line) > 4 && bytes.HasPrefix(line, []byte("year")) && unicode.IsSpace(rune(line[4])):
+		return bytes.TrimSpace(line[4:]), true
+	case len(line) > 1 && line[0] == 'Y' && (unicode.IsSpace(rune(line[1])) || isDigit(line[1])):
+		return bytes.TrimSpace(line[1:]), true
+	}
+	return nil, false
+}
+
+func matchApplyAccount(line []byte) (string, bool) {
+	const directive = "apply account"
+	if len(line) <= len(directive) || !bytes.HasPrefix(line, []byte(directive)) ||
+		!unicode.IsSpace(rune(line[len(directive)])) {
+		return "", false
+	}
+	return strings.TrimSpace(string(line[len(directive):])), true
+}
+
+// 'end apply account', or 'end apply' in ledger
+func matchEndApply(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("end apply"))
+}
+
+func matchCommentBlock(line []byte) bool {
+	return bytes.Equal(bytes.TrimRightFunc(line, unicode.IsSpace), []byte("comment"))
+}
+
+func matchEndComment(line []byte) bool {
+	return bytes.Equal(bytes.TrimRightFunc(line, unicode.IsSpace), []byte("end comment"))
+}
+
+// 'payee' and 'tag' declare the names for hledger's checks, with
+// optional sub-directives on the following indented lines
+func matchDeclaration(line []byte) bool {
+	for _, directive := range []string{"payee", "tag"} {
+		if len(line) > len(directive) && bytes.HasPrefix(line, []byte(directive)) &&
+			unicode.IsSpace(rune(line[len(directive)])) {
+			return true
+		}
+	}
+	return false
+}
+
+// 'commodity VTI', or with a sample amount: 'commodity $1,000.00'
+func matchCommodity(line []byte) ([]byte, bool) {
+	const directive = "commodity"
+	if len(line) <= len(directive) || !bytes.HasPrefix(line, []byte(directive)) ||
+		!unicode.IsSpace(rune(line[len(directive)])) {
+		return nil, false
+	}
+	return bytes.TrimSpace(line[len(directive):]), true
+}
+
+// the settings of the planning tools, one per line:
+// 'fire:withdrawal-rate 3.5%'
+func matchSetting(line []byte) (key, value string, ok bool) {
+	if !bytes.HasPrefix(line, []byte("fire:")) {
+		return "", "", false
+	}
+	end := bytes.IndexFunc(line, unicode.IsSpace)
+	if end == -1 {
+		return string(line), "", true
+	}
+	return string(line[:end]), strings.TrimSpace(string(line[end:])), true
+}
+
+// the directives changing how the rest of the file is
+// parsed: year and apply account
+func (s *Scanner) parseState(line []byte) error {
+	if tok, ok := matchYear(line); ok {
+		year, err := strconv.Atoi(string(tok))
+		if err != nil || len(tok) != 4 {
+			return s.wrap(fmt.Errorf("year must be followed by a 4 digit year: '%s'", tok))
+		}
+		s.year = year
+		return nil
+	}
+	if name, ok := matchApplyAccount(line); ok {
+		if name == "" {
+			return s.wrap(fmt.Errorf("apply account must be followed by an account"))
+		}
+		// cloned, the chunks of a parallel parse keep the previous state
+		s.accounts = append(slices.Clone(s.accounts), name)
+		return nil
+	}
+	if matchEndApply(line) {
+		if len(s.accounts) == 0 {
+			return s.wrap(fmt.Errorf("end apply account without apply account"))
+		}
+		s.accounts = s.accounts[:len(s.accounts)-1]
+		return nil
+	}
+	return ErrNoMatch
+}
+
+// the content of a 'comment' ... 'end comment' block, the
+// block ends at the end of the file if 'end comment' is missing
+func (s *Scanner) ParseCommentBlock(line []byte) (text string, err error) {
+	if !matchCommentBlock(line) {
+		return "", ErrNoMatch
+	}
+	row := s.row
+	var lines []string
+	for s.Scan() {
+		if matchEndComment(s.Bytes()) {
+			break
+		}
+		lines = append(lines, s.Text())
+	}
+	text = strings.Join(lines, "\n")
+	s.journal.Notes = append(s.journal.Notes, Note{File: s.filename, Row: row, Text: text})
+	return text, s.Err()
+}
+
+// comment lines at column 0 are kept as notes, consecutive
+// lines are joined
+func (s *Scanner) ParseNote(line []byte) error {
+	if len(line) == 0 || (line[0] != START_OF_COMMENT && strings.IndexByte(lineComments, line[0]) == -1) {
+		return ErrNoMatch
+	}
+	text := string(bytes.TrimRightFunc(line[1:], unicode.IsSpace))
+	text = strings.TrimPrefix(text, " ")
+
+	notes := s.journal.Notes
+	if len(notes) > 0 && s.noteRow > 0 && s.noteRow == s.row-1 {
+		notes[len(notes)-1].Text += "\n" + text
+	} else {
+		s.journal.Notes = append(notes, Note{File: s.filename, Row: s.row, Text: text})
+	}
+	s.noteRow = s.row
+	return nil
+}
+
+// prefixes the account with the 'apply account' directives in effect
+func (s *Scanner) applyAccount(name string) string {
+	if len(s.accounts) == 0 {
+		return name
+	}
+	return strings.Join(s.accounts, ":") + ":" + name
+}
+
+// the metadata of a commodity are on the indented lines following
+// the directive, as comments or not: '  ; class: us-equity'. Other
+// sub-directives (format, note) are skipped. Returns false at the
+// end of the directive
+func (s *Scanner) parseCommodityMeta(line []byte) bool {
+	if len(line) == 0 || !unicode.IsSpace(rune(line[0])) {
+		s.commodity = ""
+		return false
+	}
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		s.commodity = ""
+		return false
+	}
+	line = bytes.TrimSpace(bytes.TrimLeft(line, ";"))
+	i := bytes.IndexByte(line, ':')
+	if i < 1 || bytes.ContainsAny(line[:i], " \t") {
+		return true
+	}
+	s.journal.Commodities[s.commodity][string(line[:i])] = string(bytes.TrimSpace(line[i+1:]))
+	return true
+}
+
+// metadata are comments of the form 'key: value', the key
+// can't contain spaces (so regular comments are skipped)
+func (tx *Transaction) addMeta(comment []byte) {
+	comment = bytes.TrimSpace(comment)
+	i := bytes.IndexByte(comment, ':')
+	if i < 1 || bytes.ContainsAny(comment[:i], " \t") {
+		return
+	}
+	if i+1 < len(comment) && !unicode.IsSpace(rune(comment[i+1])) {
+		return
+	}
+	if tx.Meta == nil {
+		tx.Meta = make(map[string]string)
+	}
+	tx.Meta[string(comment[:i])] = string(bytes.TrimSpace(comment[i+1:]))
+}
@@ -5,10 +5,10 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/shopspring/decimal"
 )
@@ -27,78 +27,18 @@ import (
 // speed and correctness is critical
 //
 
+// ParseJournal reads the journal and its includes. The balance
+// assertions are not checked, a statement that doesn't add up
+// shouldn't prevent reading the journal: see CheckBalanceAssertions
 func ParseJournal(filepath string) (Journal, []Transaction, error) {
 
-	file, err := os.Open(filepath)
+	journal, transactions, parseErrs, err := parseJournalFile(filepath)
 	if err != nil {
 		log.Printf("failed to open '%s' (%s)\r\n", filepath, err)
 		return Journal{}, []Transaction{}, err
 	}
-	defer file.Close()
-
-	errs := ParseErrors{}
-	transactions := []Transaction{}
-
-	journal := Journal{
-		Filepath:        filepath,
-		Decimal:         DefaultNumberFormat.Decimal,
-		DefaultCurrency: DefaultCurrency,
-		Alias:           make(map[string]string),
-		Includes:        make([]Journal, 0),
-	}
-
-	s := Scanner{
-		filename: filepath,
-		journal:  &journal,
-		Scanner:  bufio.NewScanner(file),
-	}
-
-	for s.Scan() {
-		line := s.Bytes()
-
-		// trim comments, skip empty lines
-		line, empty, _ := tidy(line)
-		if empty {
-			continue
-		}
-
-		// check for transaction (common case)
-		tx, err := s.ParseTransaction(line)
-		if err == nil {
-			transactions = append(transactions, tx)
-			continue
-		} else if err != ErrNoMatch {
-			errs.add(err)
-			continue
-		}
-
-		// check for directives (rare case)
-		txs, err := s.ParseDirective(&journal, line)
-		if err == nil {
-			if len(txs) > 0 {
-				transactions = append(transactions, txs...)
-			}
-			continue
-		} else if err != nil {
-			errs.add(err)
-			continue
-		}
-
-		errs.add(s.wrap(fmt.Errorf("skiped line: '%s'", line)))
-	}
-
-	err = s.Err()
-	if err != nil {
-		errs.add(err)
-	}
-
-	for i := 0; i < len(transactions); i++ {
-		err := balanceTransaction(&transactions[i])
-		if err != nil {
-			errs.add(err)
-		}
-	}
 
+	errs := ParseErrors{errors: parseErrs}
 	return journal, transactions, errs.get()
 }
 
@@ -152,10 +92,16 @@ func (s *Scanner) ParseTransaction(line []byte) (tx Transaction, err error) {
 		Postings: make([]Posting, 0, 2),
 	}
 
-	tx.Pending, tail, err = s.ParseTxPending(tail)
+	tx.Cleared, tail, err = s.ParseTxCleared(tail)
 	if err != nil {
 		return
 	}
+	if !tx.Cleared {
+		tx.Pending, tail, err = s.ParseTxPending(tail)
+		if err != nil {
+			return
+		}
+	}
 
 	tx.Code, tail, err = s.ParseTxCode(tail)
 	if err != nil {
@@ -167,34 +113,59 @@ func (s *Scanner) ParseTransaction(line []byte) (tx Transaction, err error) {
 		return
 	}
 
+	// the comment trimmed from the tx line may hold metadata
+	if raw := s.Bytes(); len(raw) > len(line) && raw[len(line)] == START_OF_COMMENT {
+		tx.addMeta(raw[len(line)+1:])
+	}
+
 	// tx postings are indented on the following lines
-	for s.Scan() {
-		var empty bool
-		var hadComment bool
+	err = s.parsePostings(&tx)
+	return
+}
 
+// the postings are indented on the lines following the header, up
+// to an empty line
+func (s *Scanner) parsePostings(tx *Transaction) error {
+	for s.Scan() {
 		// check for end of tx postings
-		line, empty, hadComment = tidy(s.Bytes())
+		line, empty, hadComment := tidy(s.Bytes())
 		if empty {
 			if hadComment {
+				tx.addMeta(s.Bytes()[len(line)+1:])
 				continue
 			}
 			break
 		}
 
-		tail, err = s.ParseIndent(line)
+		tail, err := s.ParseIndent(line)
 		if err != nil {
-			return
+			return err
 		}
 
 		var post Posting
 		post.Account, tail, err = s.ParseAcctName(tail)
 		if err != nil {
-			return
+			return err
+		}
+		post.Account = s.applyAccount(post.Account)
+
+		// balance assertion follows the amount
+		var assertion []byte
+		if i := bytes.IndexByte(tail, '='); i != -1 {
+			assertion = tail[i+1:]
+			tail = bytes.TrimSpace(tail[:i])
 		}
 
 		post.Lot, tail, err = s.ParseLot(tail)
 		if err != nil {
-			return
+			return err
+		}
+
+		if assertion != nil {
+			post.Assertion, err = s.ParseAssertion(bytes.TrimSpace(assertion))
+			if err != nil {
+				return err
+			}
 		}
 
 		if len(tail) > 0 {
@@ -203,7 +174,46 @@ func (s *Scanner) ParseTransaction(line []byte) (tx Transaction, err error) {
 
 		tx.Postings = append(tx.Postings, post)
 	}
+	return nil
+}
+
+// a periodic transaction, the budget of its postings for each
+// period: '~ monthly', '~ quarterly from 2024/04/01  description'
+func (s *Scanner) ParsePeriodicTransaction(line []byte) (budget Budget, err error) {
+	_, tail := s.advance(line, 1)
+	// the description follows two spaces, and is not used
+	if i := bytes.Index(tail, []byte("  ")); i != -1 {
+		tail = tail[:i]
+	}
+	fields := strings.Fields(string(tail))
+	if len(fields) == 0 {
+		return budget, s.wrap(fmt.Errorf("periodic transaction must be followed by a period"))
+	}
+	budget.Period, err = ParsePeriod(strings.ToLower(fields[0]))
+	if err != nil {
+		return budget, s.wrap(err)
+	}
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 3 && fields[1] == "from":
+		budget.Start, _, err = s.ParseDate([]byte(fields[2]))
+		if err == ErrNoMatch {
+			return budget, s.wrap(fmt.Errorf("bad start date '%s'", fields[2]))
+		} else if err != nil {
+			return
+		}
+	default:
+		return budget, s.wrap(fmt.Errorf("unsupported period '%s', expected a period and an optional 'from' date", tail))
+	}
 
+	tx := Transaction{Date: budget.Start}
+	if err = s.parsePostings(&tx); err != nil {
+		return
+	}
+	if err = balanceTransaction(&tx); err != nil {
+		return budget, s.wrap(err)
+	}
+	budget.Postings = tx.Postings
 	return
 }
 
@@ -239,7 +249,7 @@ func balanceTransaction(tx *Transaction) error {
 	for code, balance := range balances {
 		if !balance.Equal(decimal.Zero) {
 			if missingCount > 0 {
-				inferredPost.Commodity = commodityFromCode(code)
+				inferredPost.Commodity = CommodityFromCode(code)
 				inferredPost.Amount = balance.Neg()
 				missingCount--
 			} else {
@@ -259,19 +269,74 @@ func ParsePath(cwd, incfile string) string {
 	return filepath.Join(cwd, incfile)
 }
 
-func (s *Scanner) ParseDirective(j *Journal, line []byte) (txs []Transaction, err error) {
-
-	// include path should be relative to the current journal path
-	if bytes.HasPrefix(line, []byte("include")) {
-		cwd := filepath.Dir(j.Filepath)
-		incfile := ParsePath(cwd, strings.TrimSpace(string(line[len("include"):])))
-		subj, txs, err := ParseJournal(incfile)
+// include directives are handled by the Decoder, which
+// needs to open a nested decoder for the included file
+func (s *Scanner) ParseDirective(line []byte) error {
+	if err := s.parseState(line); err != ErrNoMatch {
+		return err
+	}
+	if _, err := s.ParseCommentBlock(line); err != ErrNoMatch {
+		return err
+	}
+	if matchDeclaration(line) {
+		s.subdirectives = true
+		return nil
+	}
+	if tok, ok := matchCommodity(line); ok {
+		return s.parseCommodity(tok)
+	}
+	if key, value, ok := matchSetting(line); ok {
+		if value == "" {
+			return s.wrap(fmt.Errorf("missing value of '%s'", key))
+		}
+		if s.journal.Settings == nil {
+			s.journal.Settings = make(map[string]string)
+		}
+		s.journal.Settings[key] = value
+		return nil
+	}
+	if line[0] == '~' {
+		budget, err := s.ParsePeriodicTransaction(line)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		j.Includes = append(j.Includes, subj)
-		return txs, nil
+		s.journal.Budgets = append(s.journal.Budgets, budget)
+		return nil
 	}
+	if len(line) > 1 && line[0] == 'P' && unicode.IsSpace(rune(line[1])) {
+		price, err := s.ParsePrice(line)
+		if err != nil {
+			return err
+		}
+		s.journal.Prices = append(s.journal.Prices, price)
+		return nil
+	}
+	return ErrNoMatch
+}
 
-	return nil, ErrNoMatch
+// the code of a commodity directive, the metadata are on the
+// following lines
+func (s *Scanner) parseCommodity(tok []byte) error {
+	code := string(bytes.Trim(tok, `"`))
+	if bytes.ContainsFunc(tok, unicode.IsDigit) {
+		_, com, tail, err := s.ParseCommodity(tok)
+		if err != nil {
+			return err
+		}
+		if len(tail) > 0 {
+			return s.wrap(fmt.Errorf("unexpected tokens after commodity: '%s'", tail))
+		}
+		code = com.Code
+	}
+	if code == "" {
+		return s.wrap(fmt.Errorf("commodity must be followed by a code"))
+	}
+	if s.journal.Commodities == nil {
+		s.journal.Commodities = make(map[string]map[string]string)
+	}
+	if s.journal.Commodities[code] == nil {
+		s.journal.Commodities[code] = make(map[string]string)
+	}
+	s.commodity = code
+	return nil
 }
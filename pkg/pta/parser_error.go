@@ -23,6 +23,11 @@ func (e *ParseErrors) Error() string {
 	return sb.String()
 }
 
+// Unwrap returns the errors, one per line of Error
+func (e *ParseErrors) Unwrap() []error {
+	return e.errors
+}
+
 func (e *ParseErrors) get() error {
 	if len(e.errors) == 0 {
 		return nil
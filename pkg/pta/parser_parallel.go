@@ -0,0 +1,276 @@
+package pta
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"unicode"
+)
+
+// Large journals are split into chunks at transaction
+// boundaries, and the chunks are parsed concurrently.
+//
+// A boundary is a date at column 0 following a blank line:
+// the blank line always ends the postings of the previous
+// transaction, so the scanner is back at the top level and
+// starting a new decoder there gives the same results as
+// reading the file from the start. Each chunk's scanner
+// starts counting rows at the chunk offset, so the errors
+// and their positions are identical to a sequential parse.
+//
+// Included files are parsed ahead of time, concurrently
+// with the chunks, and are replayed by the chunk decoder
+// when it reaches the include directive
+//
+// The year and apply account directives change how the
+// rest of the file is parsed, the splitter tracks them and
+// each chunk starts with the state at its offset. Comment
+// blocks are never split
+
+// chunks smaller than this are not worth a goroutine
+const minChunkSize = 64 * 1024
+
+type chunkResult struct {
+	journal Journal
+	txs     []Transaction
+	errs    []error
+}
+
+// an included journal being parsed ahead of time
+type prefetch struct {
+	done    chan struct{}
+	path    string
+	journal Journal
+	txs     []Transaction
+	errs    []error
+	openErr error
+}
+
+// replays the include results: errors first, then the transactions.
+// Only the relative order of the errors, and of the transactions, is
+// kept by ParseJournal so this is equivalent to the sequential parse
+func (p *prefetch) next() (tx Transaction, err error, ok bool) {
+	if len(p.errs) > 0 {
+		err, p.errs = p.errs[0], p.errs[1:]
+		return Transaction{}, err, true
+	}
+	if len(p.txs) > 0 {
+		tx, p.txs = p.txs[0], p.txs[1:]
+		return tx, nil, true
+	}
+	return Transaction{}, nil, false
+}
+
+func parseJournalFile(path string) (journal Journal, txs []Transaction, errs []error, openErr error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Journal{}, []Transaction{}, nil, err
+	}
+	journal, txs, errs = parseJournalBytes(path, data)
+	journal.Version = hashVersion(data)
+	return
+}
+
+func parseJournalBytes(path string, data []byte) (Journal, []Transaction, []error) {
+	return parseJournalChunks(path, data, chunkSize(len(data)))
+}
+
+func parseJournalChunks(path string, data []byte, size int) (Journal, []Transaction, []error) {
+	chunks, rows, states := splitJournal(data, size)
+	includes := prefetchIncludes(path, data)
+
+	results := make([]chunkResult, len(chunks))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+
+	for i := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = parseChunk(path, chunks[i], rows[i], states[i], includes)
+		}(i)
+	}
+	wg.Wait()
+
+	// merge the results in file order, a single chunk is
+	// merged into an empty journal like the others
+	var journal Journal
+	txs := []Transaction{}
+	var errs []error
+	for _, res := range results {
+		journal.merge(res.journal)
+		txs = append(txs, res.txs...)
+		errs = append(errs, res.errs...)
+	}
+	return journal, txs, errs
+}
+
+// merge adds the directives of the next part of the same file, the
+// ones changing a single value are kept when set by the part
+func (j *Journal) merge(next Journal) {
+	if j.Filepath == "" {
+		j.Filepath = next.Filepath
+	}
+	if j.Version == "" {
+		j.Version = next.Version
+	}
+	j.end = next.end
+	if j.Decimal == "" || next.Decimal != DefaultNumberFormat.Decimal {
+		j.Decimal = next.Decimal
+	}
+	if j.DefaultCurrency.Code == "" || next.DefaultCurrency.Code != DefaultCurrency.Code {
+		j.DefaultCurrency = next.DefaultCurrency
+	}
+	if j.Alias == nil {
+		j.Alias = make(map[string]string)
+	}
+	maps.Copy(j.Alias, next.Alias)
+	if j.Includes == nil {
+		j.Includes = make([]Journal, 0)
+	}
+	j.Includes = append(j.Includes, next.Includes...)
+	j.Prices = append(j.Prices, next.Prices...)
+	j.Notes = append(j.Notes, next.Notes...)
+	j.Budgets = append(j.Budgets, next.Budgets...)
+	for key, value := range next.Settings {
+		if j.Settings == nil {
+			j.Settings = make(map[string]string)
+		}
+		j.Settings[key] = value
+	}
+	for code, meta := range next.Commodities {
+		if j.Commodities == nil {
+			j.Commodities = make(map[string]map[string]string)
+		}
+		if j.Commodities[code] == nil {
+			j.Commodities[code] = make(map[string]string)
+		}
+		maps.Copy(j.Commodities[code], meta)
+	}
+}
+
+func parseChunk(path string, chunk []byte, row int, state scanState, includes map[int]*prefetch) (res chunkResult) {
+	d := newDecoder(bytes.NewReader(chunk), path, nil)
+	d.s.row = row
+	d.s.scanState = state
+	d.prefetched = includes
+	defer d.Close()
+
+	res.txs = []Transaction{}
+	for {
+		tx, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			res.errs = append(res.errs, err)
+		}
+		// unbalanced transactions are kept
+		if tx.Date != NotDate {
+			res.txs = append(res.txs, tx)
+		}
+	}
+	res.journal = *d.Journal()
+	return
+}
+
+func chunkSize(size int) int {
+	n := size / (4 * runtime.GOMAXPROCS(0))
+	if n < minChunkSize {
+		return minChunkSize
+	}
+	return n
+}
+
+// splits the journal at transaction boundaries into chunks of
+// roughly the given size, returns the chunks, the number of
+// rows preceding each chunk and the directives state at the
+// start of each chunk
+func splitJournal(data []byte, size int) (chunks [][]byte, rows []int, states []scanState) {
+	start, startRow := 0, 0
+	row := 0
+	blank := false
+	comment := false
+
+	// only tracks the state, the errors are reported by the chunks
+	var s Scanner
+	startState := s.scanState
+
+	for i := 0; i < len(data); {
+		end := bytes.IndexByte(data[i:], '\n')
+		if end == -1 {
+			end = len(data)
+		} else {
+			end += i + 1
+		}
+		line := data[i:end]
+
+		if !comment && blank && i-start >= size && matchDate(line) {
+			chunks = append(chunks, data[start:i])
+			rows = append(rows, startRow)
+			states = append(states, startState)
+			start, startRow, startState = i, row, s.scanState
+		}
+		blank = len(bytes.TrimSpace(line)) == 0
+
+		tidied, _, _ := tidy(line)
+		switch {
+		case comment:
+			comment = !matchEndComment(line)
+		case matchCommentBlock(tidied):
+			comment = true
+		case len(tidied) > 0 && !unicode.IsSpace(rune(tidied[0])):
+			s.parseState(tidied)
+		}
+		row++
+		i = end
+	}
+	chunks = append(chunks, data[start:])
+	rows = append(rows, startRow)
+	states = append(states, startState)
+	return
+}
+
+// starts parsing all the files included at column 0, keyed by
+// the row of the include directive
+func prefetchIncludes(path string, data []byte) map[int]*prefetch {
+	var includes map[int]*prefetch
+	cwd := filepath.Dir(path)
+
+	row := 0
+	comment := false
+	for i := 0; i < len(data); {
+		end := bytes.IndexByte(data[i:], '\n')
+		if end == -1 {
+			end = len(data)
+		} else {
+			end += i + 1
+		}
+		row++
+
+		line, _, _ := tidy(data[i:end])
+		if comment {
+			comment = !matchEndComment(data[i:end])
+		} else if matchCommentBlock(line) {
+			comment = true
+		} else if name, ok := matchInclude(line); ok {
+			if includes == nil {
+				includes = make(map[int]*prefetch)
+			}
+			p := &prefetch{done: make(chan struct{}), path: ParsePath(cwd, name)}
+			includes[row] = p
+			go func() {
+				defer close(p.done)
+				p.journal, p.txs, p.errs, p.openErr = parseJournalFile(p.path)
+			}()
+		}
+		i = end
+	}
+	return includes
+}
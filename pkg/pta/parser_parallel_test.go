@@ -0,0 +1,182 @@
+package pta
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitJournal(t *testing.T) {
+	data := []byte("2023/11/01 a\n" +
+		"    assets:cash  $1\n" +
+		"    income\n" +
+		"\n" +
+		"2023/11/02 b\n" +
+		"    assets:cash  $1\n" +
+		"    income\n" +
+		"2023/11/03 not a boundary, no blank line\n" +
+		"\n" +
+		"  \n" +
+		"2023/11/04 c\n")
+
+	chunks, rows, _ := splitJournal(data, 1)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+	expectedRows := []int{0, 4, 10}
+	for i, row := range rows {
+		if row != expectedRows[i] {
+			t.Errorf("chunk %d: expected row %d, got %d", i, expectedRows[i], row)
+		}
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Errorf("chunks do not add up to the input")
+	}
+
+	// big chunks do not split
+	chunks, _, _ = splitJournal(data, len(data))
+	if len(chunks) != 1 {
+		t.Errorf("expected 1 chunk, got %d", len(chunks))
+	}
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	// mix of good transactions, errors and includes
+	parts := []string{"./test/err.journal", "./test/test.journal", "./test/bench2.journal"}
+	var buf bytes.Buffer
+	for i := 0; i < 20; i++ {
+		for _, part := range parts {
+			data, err := os.ReadFile(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf.Write(data)
+			buf.WriteString("\n\n")
+		}
+	}
+	data := buf.Bytes()
+	path := "./test/generated.journal"
+
+	// sequential
+	var seqTxs []Transaction
+	var seqErrs []error
+	d := newDecoder(bytes.NewReader(data), path, nil)
+	defer d.Close()
+	for {
+		tx, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			seqErrs = append(seqErrs, err)
+		}
+		if tx.Date != NotDate {
+			seqTxs = append(seqTxs, tx)
+		}
+	}
+
+	journal, txs, errs := parseJournalChunks(path, data, 1)
+
+	if len(txs) != len(seqTxs) {
+		t.Fatalf("expected %d transactions, got %d", len(seqTxs), len(txs))
+	}
+	for i := range txs {
+		if WriteTransaction(txs[i]) != WriteTransaction(seqTxs[i]) {
+			t.Errorf("transaction %d does not match", i)
+		}
+	}
+	if len(errs) != len(seqErrs) {
+		t.Fatalf("expected %d errors, got %d", len(seqErrs), len(errs))
+	}
+	for i := range errs {
+		if errs[i].Error() != seqErrs[i].Error() {
+			t.Errorf("error %d does not match:\n%s\n%s", i, errs[i], seqErrs[i])
+		}
+	}
+	if len(journal.Notes) != len(d.Journal().Notes) {
+		t.Errorf("expected %d notes, got %d", len(d.Journal().Notes), len(journal.Notes))
+	}
+	if len(journal.Includes) != len(d.Journal().Includes) {
+		t.Errorf("expected %d includes, got %d", len(d.Journal().Includes), len(journal.Includes))
+	}
+	if !strings.Contains(errs[len(errs)-1].Error(), "generated.journal:") {
+		t.Errorf("expected error positions in the generated file: %s", errs[len(errs)-1])
+	}
+}
+
+func TestParallelMergesLaterDirectives(t *testing.T) {
+	data := []byte("2024/01/01 a\n" +
+		"    checking  $1\n" +
+		"    income\n" +
+		"\n" +
+		"2024/01/02 b\n" +
+		"    checking  $1\n" +
+		"    income\n" +
+		"\n" +
+		"fire:currency $\n" +
+		"P 2024/01/02 VTI $245.10\n" +
+		"commodity VTI\n" +
+		"    class: stocks\n" +
+		"\n" +
+		"2024/01/03 c\n" +
+		"    checking  $1\n" +
+		"    income\n")
+
+	d := newDecoder(bytes.NewReader(data), "", nil)
+	defer d.Close()
+	var seqTxs []Transaction
+	for tx, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqTxs = append(seqTxs, tx)
+	}
+
+	journal, txs, errs := parseJournalChunks("", data, 1)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(txs))
+	}
+	for i := range txs {
+		if WriteTransaction(txs[i]) != WriteTransaction(seqTxs[i]) {
+			t.Errorf("transaction %d does not match:\n%s\n%s", i, WriteTransaction(txs[i]), WriteTransaction(seqTxs[i]))
+		}
+	}
+	if len(journal.Prices) != 1 || journal.Settings["fire:currency"] != "$" || journal.Commodities["VTI"]["class"] != "stocks" {
+		t.Errorf("expected the directives of the last chunk, got %+v", journal)
+	}
+	if !reflect.DeepEqual(journal, *d.Journal()) {
+		t.Errorf("expected the journal of the sequential parse\n%+v\n%+v", journal, *d.Journal())
+	}
+}
+
+func TestParallelOpenDirectives(t *testing.T) {
+	data := []byte("2024/01/01 a\n" +
+		"    checking  $1\n" +
+		"    income\n" +
+		"\n" +
+		"apply account personal\n" +
+		"\n" +
+		"2024/01/02 b\n" +
+		"    checking  $1\n" +
+		"    income\n")
+
+	for _, size := range []int{1, len(data)} {
+		journal, _, errs := parseJournalChunks("", data, size)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if !journal.OpenDirectives() {
+			t.Errorf("expected the apply account in effect at the end, chunks of %d bytes", size)
+		}
+	}
+	journal, _, _ := parseJournalChunks("", append(data, "\nend apply account\n"...), 1)
+	if journal.OpenDirectives() {
+		t.Errorf("expected no directive in effect after the end of the apply account")
+	}
+}
@@ -48,6 +48,12 @@ func TestParseDate(t *testing.T) {
 		{in: []byte("2023/11/24 tailing bytes"), out: date("2023/11/24"), tail: []byte("tailing bytes"), err: nil},
 		{in: []byte("2023/24/11 bad date"), out: NotDate, tail: []byte(""), err: fmt.Errorf("bad date")},
 		{in: []byte("2023/11/24nospace"), out: NotDate, tail: []byte(""), err: fmt.Errorf("no space")},
+		{in: []byte("2024/1/5 single digits"), out: date("2024/01/05"), tail: []byte("single digits"), err: nil},
+		{in: []byte("2024.12.5"), out: date("2024/12/05"), tail: []byte(""), err: nil},
+		{in: []byte("2023/02/29 not a leap year"), out: NotDate, tail: []byte(""), err: fmt.Errorf("bad date")},
+		{in: []byte("1/5 no year directive"), out: NotDate, tail: []byte(""), err: fmt.Errorf("no year")},
+		{in: []byte("24/1/5 short year"), out: NotDate, tail: []byte("24/1/5 short year"), err: ErrNoMatch},
+		{in: []byte("2024/123/5"), out: NotDate, tail: []byte("2024/123/5"), err: ErrNoMatch},
 	}
 
 	s := Scanner{
@@ -85,6 +91,40 @@ func TestParseDate(t *testing.T) {
 	}
 }
 
+func TestParseDateWithYear(t *testing.T) {
+	s := Scanner{filename: "TestParseDateWithYear"}
+	s.year = 2024
+
+	cases := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{in: "1/5 partial", expected: "2024/01/05"},
+		{in: "12/31", expected: "2024/12/31"},
+		{in: "02-29 leap year", expected: "2024/02/29"},
+		{in: "2023/3/1 full dates keep their year", expected: "2023/03/01"},
+		{in: "2/30", err: true},
+		{in: "13/1", err: true},
+	}
+	for _, c := range cases {
+		out, _, err := s.ParseDate([]byte(c.in))
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", c.in, out.Format("2006/01/02"))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.in, err)
+			continue
+		}
+		if out.Format("2006/01/02") != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.in, c.expected, out.Format("2006/01/02"))
+		}
+	}
+}
+
 func TestParseTxPending(t *testing.T) {
 	type Case struct {
 		in   []byte
@@ -136,6 +176,53 @@ func TestParseTxPending(t *testing.T) {
 	}
 }
 
+func TestParseTxCleared(t *testing.T) {
+	type Case struct {
+		in   []byte
+		out  bool
+		tail []byte
+		err  error
+	}
+
+	cases := []Case{
+		{in: []byte(""), out: false, tail: []byte(""), err: nil},
+		{in: []byte("! some bytes"), out: false, tail: []byte("! some bytes"), err: nil},
+		{in: []byte("*nospace"), out: false, tail: []byte(""), err: fmt.Errorf("no space")},
+		{in: []byte("* some bytes"), out: true, tail: []byte("some bytes"), err: nil},
+	}
+
+	s := Scanner{filename: "TestParseTxCleared"}
+
+	for _, test := range cases {
+		s.row += 1
+		s.col = 0
+
+		out, tail, err := s.ParseTxCleared(test.in)
+		if out != test.out || !bytes.Equal(tail, test.tail) || !matchErrs(err, test.err) {
+			t.Errorf("%s: got %t, '%s', %v", test.in, out, tail, err)
+		}
+	}
+
+	// round trips through the serializer
+	tx := Transaction{
+		Date:        time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Description: "cleared",
+		Cleared:     true,
+		Postings: []Posting{
+			{Account: "assets:cash", Lot: Lot{Amount: decimal.New(5, 0), Commodity: DefaultCurrency}},
+			{Account: "income:gift", Lot: Lot{Amount: decimal.New(-5, 0), Commodity: DefaultCurrency}},
+		},
+	}
+	d := NewDecoder(strings.NewReader(WriteTransaction(tx)))
+	got, err := d.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Cleared || got.Pending || got.Description != "cleared" {
+		t.Errorf("cleared flag not parsed back: %+v", got)
+	}
+}
+
 func TestParseTxCode(t *testing.T) {
 	type Case struct {
 		in   []byte
@@ -734,8 +821,8 @@ func TestBalanceTransaction(t *testing.T) {
 	// Balanced
 	tx := &Transaction{}
 	tx.Postings = []Posting{
-		{"", Lot{Amount: decimal.New(10, 0)}},
-		{"", Lot{Amount: decimal.New(-10, 0)}},
+		{Lot: Lot{Amount: decimal.New(10, 0)}},
+		{Lot: Lot{Amount: decimal.New(-10, 0)}},
 	}
 	if err := balanceTransaction(tx); err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -744,8 +831,8 @@ func TestBalanceTransaction(t *testing.T) {
 	// Unbalanced
 	tx = &Transaction{}
 	tx.Postings = []Posting{
-		{"", Lot{Amount: decimal.New(10, 0)}},
-		{"", Lot{Amount: decimal.New(-5, 0)}},
+		{Lot: Lot{Amount: decimal.New(10, 0)}},
+		{Lot: Lot{Amount: decimal.New(-5, 0)}},
 	}
 	if err := balanceTransaction(tx); err == nil {
 		t.Errorf("Expected error, got nil")
@@ -754,8 +841,8 @@ func TestBalanceTransaction(t *testing.T) {
 	// Multiple missing
 	tx = &Transaction{}
 	tx.Postings = []Posting{
-		{"", Lot{Amount: decimal.New(0, 0)}},
-		{"", Lot{Amount: decimal.New(0, 0)}},
+		{Lot: Lot{Amount: decimal.New(0, 0)}},
+		{Lot: Lot{Amount: decimal.New(0, 0)}},
 	}
 	if err := balanceTransaction(tx); err == nil {
 		t.Errorf("Expected error, got nil")
@@ -764,8 +851,8 @@ func TestBalanceTransaction(t *testing.T) {
 	// Infer missing
 	tx = &Transaction{}
 	tx.Postings = []Posting{
-		{"", Lot{Amount: decimal.New(10, 0)}},
-		{"", Lot{Amount: decimal.New(0, 0)}},
+		{Lot: Lot{Amount: decimal.New(10, 0)}},
+		{Lot: Lot{Amount: decimal.New(0, 0)}},
 	}
 	if err := balanceTransaction(tx); err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -830,7 +917,7 @@ func TestSanity(t *testing.T) {
 	_, txs, err := ParseJournal(file)
 
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 		return
 	}
 
@@ -954,3 +1041,97 @@ func TestSanity(t *testing.T) {
 	}
 
 }
+
+func TestParseMetaAndAssertion(t *testing.T) {
+	j := Journal{DefaultCurrency: DefaultCurrency}
+	in := "2024/01/31 statement ; fitid: 2024013101\n" +
+		"    ; bank: BMO\n" +
+		"    ; just a comment, not metadata\n" +
+		"    assets:bmo:checking    $0 = $1,234.56\n" +
+		"\n" +
+		"2024/02/01 payday\n" +
+		"    assets:bmo:checking    $100 = $1,334.56 ; after pay\n" +
+		"    income:employer\n"
+
+	txs, err := j.ParseTransactionStrings(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+
+	meta := txs[0].Meta
+	if len(meta) != 2 || meta["fitid"] != "2024013101" || meta["bank"] != "BMO" {
+		t.Errorf("unexpected metadata: %v", meta)
+	}
+	if txs[1].Meta != nil {
+		t.Errorf("expected no metadata, got: %v", txs[1].Meta)
+	}
+
+	assertion := txs[0].Postings[0].Assertion
+	if assertion == nil || !assertion.Decimal.Equal(decimal.RequireFromString("1234.56")) {
+		t.Errorf("unexpected balance assertion: %+v", assertion)
+	}
+	post := txs[1].Postings[0]
+	if !post.Amount.Equal(decimal.New(100, 0)) || post.Assertion == nil {
+		t.Errorf("unexpected posting: %+v", post)
+	}
+
+	// serialized metadata and assertions parse back the same
+	again, err := j.ParseTransactionStrings(WriteTransaction(txs[0]) + WriteTransaction(txs[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again[0].Meta, meta) {
+		t.Errorf("metadata did not round trip: %v", again[0].Meta)
+	}
+	if !again[1].Postings[0].Assertion.Decimal.Equal(post.Assertion.Decimal) {
+		t.Errorf("assertion did not round trip: %+v", again[1].Postings[0].Assertion)
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	in := "P 2024/01/15 VTI $245.10\n" +
+		"P 2024-01-10 EUR 1.47 CAD\n" +
+		"\n" +
+		"2024/01/16 tx\n" +
+		"    assets:cash    $10\n" +
+		"    income:gift\n" +
+		"\n" +
+		"P 2024/01/17 VTI\n"
+
+	d := NewDecoder(strings.NewReader(in))
+	var errs []error
+	for _, err := range d.All() {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing price") {
+		t.Errorf("expected a missing price error, got %v", errs)
+	}
+
+	prices := d.Journal().AllPrices()
+	if len(prices) != 2 {
+		t.Fatalf("expected 2 prices, got %d", len(prices))
+	}
+	if prices[0].Code != "EUR" || prices[0].Commodity.Code != "CAD" || !prices[0].Decimal.Equal(decimal.RequireFromString("1.47")) {
+		t.Errorf("unexpected price %+v", prices[0])
+	}
+	if prices[1].Code != "VTI" || prices[1].Commodity != DefaultCurrency {
+		t.Errorf("unexpected price %+v", prices[1])
+	}
+
+	// round trip
+	d = NewDecoder(strings.NewReader(WritePrice(prices[0])))
+	for _, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(d.Journal().Prices) != 1 || !d.Journal().Prices[0].Decimal.Equal(prices[0].Decimal) ||
+		d.Journal().Prices[0].Commodity != prices[0].Commodity {
+		t.Errorf("price not parsed back: %+v", d.Journal().Prices)
+	}
+}
@@ -0,0 +1,66 @@
+package pta
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period is the length of the intervals of a report
+type Period int
+
+const (
+	Monthly Period = iota
+	Quarterly
+	Yearly
+)
+
+func ParsePeriod(period string) (Period, error) {
+	switch period {
+	case "monthly":
+		return Monthly, nil
+	case "quarterly":
+		return Quarterly, nil
+	case "yearly":
+		return Yearly, nil
+	}
+	return 0, fmt.Errorf("unknown period '%s' (monthly, quarterly or yearly)", period)
+}
+
+func (p Period) String() string {
+	switch p {
+	case Quarterly:
+		return "quarterly"
+	case Yearly:
+		return "yearly"
+	}
+	return "monthly"
+}
+
+// Start is the first day of the period containing the date
+func (p Period) Start(date time.Time) time.Time {
+	month := date.Month()
+	switch p {
+	case Quarterly:
+		month -= (month - 1) % 3
+	case Yearly:
+		month = time.January
+	}
+	return time.Date(date.Year(), month, 1, 0, 0, 0, 0, date.Location())
+}
+
+// Next is the first day of the following period
+func (p Period) Next(date time.Time) time.Time {
+	start := p.Start(date)
+	switch p {
+	case Quarterly:
+		return start.AddDate(0, 3, 0)
+	case Yearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// End is the last day of the period containing the date
+func (p Period) End(date time.Time) time.Time {
+	return p.Next(date).AddDate(0, 0, -1)
+}
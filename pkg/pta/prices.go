@@ -0,0 +1,32 @@
+package pta
+
+import "slices"
+
+// AllPrices returns the prices of the journal and its includes,
+// sorted by date
+func (j Journal) AllPrices() []Price {
+	prices := slices.Clone(j.Prices)
+	for _, inc := range j.Includes {
+		prices = append(prices, inc.AllPrices()...)
+	}
+	slices.SortStableFunc(prices, func(a, b Price) int {
+		return a.Date.Compare(b.Date)
+	})
+	return prices
+}
+
+// TransactionPrices returns the prices implied by the unit values
+// of the postings, '4 VTI @ $240' is a price of VTI at the date of
+// the transaction
+func TransactionPrices(txs []Transaction) []Price {
+	var prices []Price
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			if post.UnitValue.Decimal.IsZero() || post.UnitValue.Code == post.Commodity.Code {
+				continue
+			}
+			prices = append(prices, Price{Date: tx.Date, Code: post.Commodity.Code, Value: post.UnitValue})
+		}
+	}
+	return prices
+}
@@ -0,0 +1,261 @@
+package pta
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The return of investment accounts separates the money put in
+// or taken out (postings from the other accounts: bank, opening
+// balances) from what the investments earned: income and fees
+// (postings from income and expense accounts) and the changes of
+// the market value. The money-weighted return (XIRR) depends on
+// the timing of the contributions, the time-weighted return
+// doesn't, it compares the investments regardless of the flows
+
+// the balancing account of trades, moves within the investments
+const tradingAccount = "equity:trading"
+
+// Performance is the return of the investment accounts between
+// the end of the day before Start and the end of End
+type Performance struct {
+	Start, End    time.Time
+	StartValue    decimal.Decimal
+	EndValue      decimal.Decimal
+	Contributions decimal.Decimal // put in the accounts
+	Withdrawals   decimal.Decimal // taken out, positive
+	Income        decimal.Decimal // dividends and interest, less the fees
+	Gain          decimal.Decimal // income and market value changes
+	XIRR          float64         // money-weighted, yearly, NaN when undefined
+	TWR           float64         // time-weighted, over the period
+}
+
+type InvestmentReport struct {
+	Target   Commodity
+	Periods  []Performance
+	Total    Performance // from the first transaction
+	Warnings []string
+}
+
+// a transaction of the investment accounts, valued at its date
+type investmentEvent struct {
+	date     time.Time
+	postings []Posting // of the investment accounts
+	flow     decimal.Decimal
+	income   decimal.Decimal
+}
+
+// InvestmentReturn computes the returns of the accounts matching
+// the pattern (a regular expression) for each period, up to the
+// last transaction or price, in the target commodity
+func InvestmentReturn(txs []Transaction, prices []Price, accountPattern string, period Period, target Commodity) (InvestmentReport, error) {
+	pattern, err := regexp.Compile(accountPattern)
+	if err != nil {
+		return InvestmentReport{}, fmt.Errorf("bad account pattern: %s", err)
+	}
+	report := InvestmentReport{Target: target}
+	// the prices of the trades fill in the gaps of the price history
+	history := NewPriceHistory(append(TransactionPrices(txs), prices...))
+	warn := func(warning string) {
+		if !slices.Contains(report.Warnings, warning) {
+			report.Warnings = append(report.Warnings, warning)
+		}
+	}
+	value := func(amount decimal.Decimal, code string, date time.Time) decimal.Decimal {
+		v, _, found := history.Convert(amount, code, target.Code, date)
+		if !found {
+			warn(fmt.Sprintf("no price for %s in %s on %s", code, target.Code, date.Format("2006/01/02")))
+		}
+		return v
+	}
+
+	var events []investmentEvent
+	var last time.Time
+	for _, tx := range txs {
+		event := investmentEvent{date: tx.Date}
+		for _, post := range tx.Postings {
+			if pattern.MatchString(post.Account) {
+				event.postings = append(event.postings, post)
+			}
+		}
+		if len(event.postings) == 0 {
+			continue
+		}
+		for _, post := range tx.Postings {
+			switch {
+			case pattern.MatchString(post.Account) || strings.HasPrefix(post.Account, tradingAccount):
+			case strings.Contains(post.Account, "income") || strings.Contains(post.Account, "revenue") ||
+				strings.Contains(post.Account, "expense"):
+				event.income = event.income.Sub(value(post.Amount, post.Commodity.Code, tx.Date))
+			default:
+				event.flow = event.flow.Sub(value(post.Amount, post.Commodity.Code, tx.Date))
+			}
+		}
+		events = append(events, event)
+		if tx.Date.After(last) {
+			last = tx.Date
+		}
+	}
+	if len(events) == 0 {
+		return report, fmt.Errorf("no transactions in the accounts matching '%s'", accountPattern)
+	}
+	slices.SortStableFunc(events, func(a, b investmentEvent) int {
+		return a.date.Compare(b.date)
+	})
+	for _, price := range prices {
+		if price.Date.After(last) {
+			last = price.Date
+		}
+	}
+
+	first := events[0].date
+	for next := period.Start(first); !next.After(last); next = period.Next(next) {
+		start, end := next, period.End(next)
+		if end.After(last) {
+			end = last
+		}
+		if start.Before(first) {
+			start = first
+		}
+		report.Periods = append(report.Periods, performance(events, start, end, value))
+	}
+	report.Total = performance(events, first, last, value)
+	return report, nil
+}
+
+func performance(events []investmentEvent, start, end time.Time, value func(decimal.Decimal, string, time.Time) decimal.Decimal) Performance {
+	p := Performance{Start: start, End: end}
+	balances := make(map[string]decimal.Decimal)
+	worth := func(date time.Time) decimal.Decimal {
+		var total decimal.Decimal
+		for code, amount := range balances {
+			if !amount.IsZero() {
+				total = total.Add(value(amount, code, date))
+			}
+		}
+		return total
+	}
+
+	before := start.AddDate(0, 0, -1)
+	i := 0
+	for ; i < len(events) && events[i].date.Before(start); i++ {
+		for _, post := range events[i].postings {
+			balances[post.Commodity.Code] = balances[post.Commodity.Code].Add(post.Amount)
+		}
+	}
+	p.StartValue = worth(before)
+
+	// the flows of each day for the XIRR, and the value after the
+	// flows of the day for the TWR
+	flows := []cashFlow{{before, p.StartValue.Neg().InexactFloat64()}}
+	previous := p.StartValue
+	growth := 1.0
+	for i < len(events) && !events[i].date.After(end) {
+		date := events[i].date
+		var flow decimal.Decimal
+		for ; i < len(events) && events[i].date.Equal(date); i++ {
+			for _, post := range events[i].postings {
+				balances[post.Commodity.Code] = balances[post.Commodity.Code].Add(post.Amount)
+			}
+			flow = flow.Add(events[i].flow)
+			p.Income = p.Income.Add(events[i].income)
+		}
+		if flow.IsZero() {
+			continue
+		}
+		if flow.IsPositive() {
+			p.Contributions = p.Contributions.Add(flow)
+		} else {
+			p.Withdrawals = p.Withdrawals.Sub(flow)
+		}
+		flows = append(flows, cashFlow{date, flow.Neg().InexactFloat64()})
+
+		current := worth(date)
+		if !previous.IsZero() {
+			growth *= current.Sub(flow).Div(previous).InexactFloat64()
+		}
+		previous = current
+	}
+	p.EndValue = worth(end)
+	if !previous.IsZero() {
+		growth *= p.EndValue.Div(previous).InexactFloat64()
+	}
+	p.TWR = growth - 1
+	p.Gain = p.EndValue.Sub(p.StartValue).Sub(p.Contributions).Add(p.Withdrawals)
+	flows = append(flows, cashFlow{end, p.EndValue.InexactFloat64()})
+	p.XIRR = xirr(flows)
+	return p
+}
+
+type cashFlow struct {
+	date   time.Time
+	amount float64 // received, negative when paid
+}
+
+// xirr finds the yearly rate making the net present value of the
+// flows zero, with Newton's method then bisection
+func xirr(flows []cashFlow) float64 {
+	npv := func(rate float64) (value, derivative float64) {
+		for _, f := range flows {
+			years := f.date.Sub(flows[0].date).Hours() / 24 / 365
+			discount := math.Pow(1+rate, years)
+			value += f.amount / discount
+			derivative -= years * f.amount / (discount * (1 + rate))
+		}
+		return
+	}
+
+	var paid, received bool
+	var scale float64
+	for _, f := range flows {
+		paid = paid || f.amount < 0
+		received = received || f.amount > 0
+		scale += math.Abs(f.amount)
+	}
+	tolerance := scale * 1e-10
+	if !paid || !received {
+		return math.NaN()
+	}
+
+	rate := 0.1
+	for range 50 {
+		value, derivative := npv(rate)
+		if math.Abs(value) < tolerance {
+			return rate
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		rate = next
+	}
+
+	low, high := -0.9999, 100.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if lowValue*highValue > 0 {
+		return math.NaN()
+	}
+	for range 200 {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < tolerance {
+			return mid
+		}
+		if lowValue*midValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+	return (low + high) / 2
+}
@@ -0,0 +1,110 @@
+package pta
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const returnsJournal = `
+2024/01/01 Deposit
+	assets:brokerage:cash  $1,000.00
+	assets:bank
+
+2024/01/02 Buy
+	assets:brokerage:vti   10 VTI @ $100.00
+	equity:trading        -10 VTI
+	equity:trading         $1,000.00
+	assets:brokerage:cash -$1,000.00
+
+P 2024/06/30 VTI $110.00
+
+2024/07/01 Deposit and buy
+	assets:brokerage:vti   10 VTI @ $110.00
+	equity:trading        -10 VTI
+	equity:trading         $1,100.00
+	assets:bank           -$1,100.00
+
+2024/09/30 Dividend
+	assets:brokerage:cash  $50.00
+	income:dividends
+
+2024/10/01 Groceries
+	expenses:food  $80.00
+	assets:bank
+
+P 2024/12/31 VTI $121.00
+`
+
+func TestInvestmentReturn(t *testing.T) {
+	journal, txs := parseTestJournal(t, returnsJournal)
+	report, err := InvestmentReturn(txs, journal.AllPrices(), "^assets:brokerage", Yearly, DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Periods) != 1 || len(report.Warnings) != 0 {
+		t.Fatalf("expected a year without warnings, got %+v", report)
+	}
+
+	p := report.Total
+	expected := map[string][2]decimal.Decimal{
+		"start value":   {p.StartValue, decimal.Zero},
+		"end value":     {p.EndValue, decimal.New(2470, 0)},
+		"contributions": {p.Contributions, decimal.New(2100, 0)},
+		"withdrawals":   {p.Withdrawals, decimal.Zero},
+		"income":        {p.Income, decimal.New(50, 0)},
+		"gain":          {p.Gain, decimal.New(370, 0)},
+	}
+	for name, values := range expected {
+		if !values[0].Equal(values[1]) {
+			t.Errorf("expected a %s of %s, got %s", name, values[1], values[0])
+		}
+	}
+	if p.End.Format(time.DateOnly) != "2024-12-31" {
+		t.Errorf("expected to end at the last price, got %s", p.End)
+	}
+
+	// 10% until july, then 2470 / 2200
+	if math.Abs(p.TWR-(1.1*2470/2200-1)) > 1e-9 {
+		t.Errorf("unexpected time-weighted return %v", p.TWR)
+	}
+	// the flows are worth nothing at the XIRR
+	npv := -1000/math.Pow(1+p.XIRR, 1/365.0) - 1100/math.Pow(1+p.XIRR, 183/365.0) + 2470/math.Pow(1+p.XIRR, 366/365.0)
+	if math.IsNaN(p.XIRR) || math.Abs(npv) > 1e-6 {
+		t.Errorf("unexpected money-weighted return %v (npv %v)", p.XIRR, npv)
+	}
+
+	quarterly, err := InvestmentReturn(txs, journal.AllPrices(), "^assets:brokerage", Quarterly, DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quarterly.Periods) != 4 {
+		t.Fatalf("expected 4 quarters, got %d", len(quarterly.Periods))
+	}
+	q3 := quarterly.Periods[2]
+	if !q3.StartValue.Equal(decimal.New(1100, 0)) || !q3.Contributions.Equal(decimal.New(1100, 0)) ||
+		!q3.EndValue.Equal(decimal.New(2250, 0)) {
+		t.Errorf("unexpected third quarter %+v", q3)
+	}
+
+	if _, err := InvestmentReturn(txs, nil, "^assets:retirement", Yearly, DefaultCurrency); err == nil {
+		t.Error("expected an error without transactions")
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rate := xirr([]cashFlow{{start, -1000}, {start.AddDate(0, 0, 365), 1100}})
+	if math.Abs(rate-0.1) > 1e-9 {
+		t.Errorf("expected 10%%, got %v", rate)
+	}
+	rate = xirr([]cashFlow{{start, -1000}, {start.AddDate(0, 0, 365), 500}})
+	if math.Abs(rate+0.5) > 1e-9 {
+		t.Errorf("expected -50%%, got %v", rate)
+	}
+	if !math.IsNaN(xirr([]cashFlow{{start, 1000}})) {
+		t.Error("expected no rate without money paid")
+	}
+}
@@ -1,26 +1,72 @@
 package pta
 
 import (
+	"io"
 	"io/fs"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/shopspring/decimal"
 )
 
+// AppendTxs appends the transactions to the journal file. The file is
+// locked while writing, and synced to disk before returning.
+//
+// If the journal was parsed from the file (it has a Version) and the
+// file changed since, nothing is written and ErrConflict is returned
 func (j *Journal) AppendTxs(txs []Transaction) error {
-	f, err := os.OpenFile(j.Filepath, os.O_APPEND|os.O_WRONLY, fs.ModeAppend)
+	sb := strings.Builder{}
+	for _, tx := range txs {
+		sb.WriteString(WriteTransaction(tx))
+	}
+	return j.appendText(sb.String())
+}
+
+// AppendPrices writes 'P' directives at the end of the journal,
+// with the same checks as AppendTxs
+func (j *Journal) AppendPrices(prices []Price) error {
+	sb := strings.Builder{}
+	for _, price := range prices {
+		sb.WriteString(WritePrice(price))
+	}
+	if len(prices) > 0 {
+		sb.WriteString("\r\n")
+	}
+	return j.appendText(sb.String())
+}
+
+func (j *Journal) appendText(text string) error {
+	f, err := os.OpenFile(j.Filepath, os.O_APPEND|os.O_RDWR, fs.ModeAppend)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	for _, tx := range txs {
-		_, err = f.WriteString(WriteTransaction(tx))
-		if err != nil {
-			return err
-		}
+	unlock, err := lockFile(f)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if j.Version != "" && j.Version != hashVersion(content) {
+		return ErrConflict
+	}
+
+	_, err = f.WriteString(text)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+
+	j.Version = hashVersion(append(content, text...))
 	return nil
 }
 
@@ -29,7 +75,9 @@ func WriteTransaction(tx Transaction) string {
 	sb.Grow(14 + len(tx.Code) + len(tx.Description))
 
 	sb.WriteString(tx.Date.Format("2006/01/02"))
-	if tx.Pending {
+	if tx.Cleared {
+		sb.WriteString(" *")
+	} else if tx.Pending {
 		sb.WriteString(" !")
 	}
 	if tx.Code != "" {
@@ -38,13 +86,25 @@ func WriteTransaction(tx Transaction) string {
 		sb.WriteString(")")
 	}
 	if tx.Description != "" {
-		if !tx.Pending && tx.Code == "" {
+		if !tx.Pending && !tx.Cleared && tx.Code == "" {
 			sb.WriteString(" ")
 		}
 		sb.WriteString(" ")
 		sb.WriteString(tx.Description)
 	}
 
+	metaKeys := make([]string, 0, len(tx.Meta))
+	for key := range tx.Meta {
+		metaKeys = append(metaKeys, key)
+	}
+	sort.Strings(metaKeys)
+	for _, key := range metaKeys {
+		sb.WriteString("\r\n\t; ")
+		sb.WriteString(key)
+		sb.WriteString(": ")
+		sb.WriteString(tx.Meta[key])
+	}
+
 	acctWidth := 0
 	for _, post := range tx.Postings {
 		if acctWidth < len(post.Account) {
@@ -70,11 +130,20 @@ func WriteTransaction(tx Transaction) string {
 			sb.WriteString(" @ ")
 			sb.WriteString(post.ValueStr())
 		}
+		if post.Assertion != nil {
+			sb.WriteString(" = ")
+			sb.WriteString(commodityStringPadded(0, post.Assertion.Commodity, post.Assertion.Decimal))
+		}
 	}
 	sb.WriteString("\r\n\r\n")
 	return sb.String()
 }
 
+func WritePrice(p Price) string {
+	return "P " + p.Date.Format("2006/01/02") + " " + p.Code + " " +
+		commodityStringPadded(0, p.Commodity, p.Decimal) + "\r\n"
+}
+
 func (p Posting) ValueStr() string {
 	return commodityStringPadded(0, p.Lot.UnitValue.Commodity, p.Lot.UnitValue.Decimal)
 }
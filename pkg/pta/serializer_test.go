@@ -1,7 +1,12 @@
 package pta
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -46,3 +51,88 @@ func TestCommodityStringPadded(t *testing.T) {
 		}
 	}
 }
+
+func TestAppendTxs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "append.journal")
+	err := os.WriteFile(path, []byte("2023/11/20 first\n    assets:cash  $10\n    income\n\n"), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal, txs, err := ParseJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := journal.Version
+
+	err = journal.AppendTxs(txs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if journal.Version == version {
+		t.Errorf("expected the version to change after appending")
+	}
+
+	// the version matches what a fresh parse sees
+	reparsed, txs, err := ParseJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reparsed.Version != journal.Version {
+		t.Errorf("version does not match the file content")
+	}
+	if len(txs) != 2 {
+		t.Errorf("expected 2 transactions, got %d", len(txs))
+	}
+
+	// changed by someone else
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("; edited\n")
+	f.Close()
+
+	err = journal.AppendTxs(txs)
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected conflict, got: %v", err)
+	}
+}
+
+func TestAppendTxsConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concurrent.journal")
+	err := os.WriteFile(path, []byte{}, 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := Transaction{
+		Date:        time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC),
+		Description: "concurrent",
+		Postings: []Posting{
+			{Account: "assets:cash", Lot: Lot{Amount: decimal.New(10, 0), Commodity: DefaultCurrency}},
+			{Account: "income", Lot: Lot{Amount: decimal.New(-10, 0), Commodity: DefaultCurrency}},
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// without a version, appends never conflict
+			j := Journal{Filepath: path}
+			if err := j.AppendTxs([]Transaction{tx, tx}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, txs, err := ParseJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 40 {
+		t.Errorf("expected 40 transactions, got %d", len(txs))
+	}
+}
@@ -1,6 +1,8 @@
 package pta
 
 import (
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -50,6 +52,20 @@ func ComputeBalanceStatement(startingBalance BalanceStatement, transactions []Tr
 	return statement
 }
 
+// the lots of the statement as transactions on the date of the lots,
+// to start the reports from the balances
+func (s BalanceStatement) transactions() []Transaction {
+	var txs []Transaction
+	for _, accounts := range []map[string][]Lot{s.assets, s.liabilities} {
+		for _, account := range slices.Sorted(maps.Keys(accounts)) {
+			for _, lot := range accounts[account] {
+				txs = append(txs, Transaction{Date: lot.Date, Postings: []Posting{{Account: account, Lot: lot}}})
+			}
+		}
+	}
+	return txs
+}
+
 func ComputeIncomeStatement(transactions []Transaction) IncomeStatement {
 	statement := IncomeStatement{
 		revenue:  make(map[string][]Lot),
@@ -101,13 +117,32 @@ func ComputeIncomeStatement(transactions []Transaction) IncomeStatement {
 	return statement
 }
 
+// Totals returns the revenue and expenses of all the accounts,
+// one lot per currency
+func (s IncomeStatement) Totals() (revenue, expenses []Lot) {
+	for _, lots := range s.revenue {
+		revenue = append(revenue, lots...)
+	}
+	for _, lots := range s.expenses {
+		expenses = append(expenses, lots...)
+	}
+	return aggregateLotsPerCode(revenue), aggregateLotsPerCode(expenses)
+}
+
 func aggregateLotsPerCode(lots []Lot) []Lot {
+	// keep the codes in order of appearance, so the
+	// result does not depend on map iteration order
 	lotsByCode := make(map[string][]Lot)
+	codes := make([]string, 0)
 	for _, lot := range lots {
+		if _, found := lotsByCode[lot.Commodity.Code]; !found {
+			codes = append(codes, lot.Commodity.Code)
+		}
 		lotsByCode[lot.Commodity.Code] = append(lotsByCode[lot.Commodity.Code], lot)
 	}
 	aggregated := make([]Lot, 0, len(lotsByCode))
-	for code, lots := range lotsByCode {
+	for _, code := range codes {
+		lots := lotsByCode[code]
 		reduced := Lot{
 			Commodity: Commodity{
 				Code: code,
@@ -121,7 +156,15 @@ func aggregateLotsPerCode(lots []Lot) []Lot {
 				totVal := reduced.Amount.Mul(reduced.UnitValue.Decimal)
 				totVal = totVal.Add(lot.Amount.Mul(lot.UnitValue.Decimal))
 				reduced.Amount = reduced.Amount.Add(lot.Amount)
+				if reduced.Amount.IsZero() {
+					// all sold, no unit value left
+					reduced.UnitValue = Value{}
+					continue
+				}
 				reduced.UnitValue.Decimal = totVal.Div(reduced.Amount)
+				if lot.UnitValue.Code != "" {
+					reduced.UnitValue.Commodity = lot.UnitValue.Commodity
+				}
 			}
 		}
 		aggregated = append(aggregated, reduced)
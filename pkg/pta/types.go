@@ -19,11 +19,34 @@ var DefaultNumberFormat = CommodityFormat{
 
 type Journal struct {
 	Filepath        string
+	Version         string // hash of the file content when parsed
 	Alias           map[string]string
 	Decimal         string
 	DefaultCurrency Commodity
-	Includes        []Journal
+	Includes        []Journal                    // a missing include is kept, without content
+	Prices          []Price                      // from 'P' directives
+	Notes           []Note                       // top level comments and comment blocks
+	Settings        map[string]string            // from 'fire:' lines, by key
+	Commodities     map[string]map[string]string // metadata of 'commodity' directives, by code
+	Budgets         []Budget                     // from '~' periodic transactions
 	ParseErrs       ParseErrors
+
+	end scanState // the directives in effect at the end of the file
+}
+
+// OpenDirectives reports whether a year or an apply account is still
+// in effect at the end of the file. The transactions appended to it
+// are then not parsed like ParseTransactionStrings does
+func (j Journal) OpenDirectives() bool {
+	return j.end.year != 0 || len(j.end.accounts) > 0
+}
+
+// Note is a comment outside of the transactions: consecutive
+// comment lines at column 0, or a comment block
+type Note struct {
+	File string
+	Row  int // of the first line
+	Text string
 }
 
 type Transaction struct {
@@ -33,11 +56,14 @@ type Transaction struct {
 	Tags        []string
 	Postings    []Posting
 	Pending     bool
+	Cleared     bool
+	Meta        map[string]string // from '; key: value' comments
 }
 
 type Posting struct {
 	Account string
 	Lot
+	Assertion *Value // balance assertion: '= amount'
 }
 
 type Lot struct {
@@ -47,6 +73,21 @@ type Lot struct {
 	Commodity
 }
 
+// Price is the market value of a commodity (by code) at a date
+type Price struct {
+	Date time.Time
+	Code string
+	Value
+}
+
+// Budget is a periodic transaction: '~ monthly', the amounts of
+// its postings are budgeted for each period
+type Budget struct {
+	Period   Period
+	Start    time.Time // 'from' date, zero when always
+	Postings []Posting
+}
+
 type Value struct {
 	decimal.Decimal
 	Commodity
@@ -0,0 +1,327 @@
+package pta
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The statements report each commodity separately, at cost. A
+// valuation converts the amounts into a single commodity with the
+// price history of the journal ('P' directives):
+//
+//   - cost: the stocks at their unit value '@', the currencies are
+//     converted at the date of each posting
+//   - then: each posting at the price of its date
+//   - end: the balances at the price of the end of the report
+//   - market: the balances at the latest known price
+//
+// Prices are used directly, inverted ('P USD CAD 1.35' converts CAD
+// to USD) or through the price currency (VTI in USD, then USD to CAD)
+
+type ValuationMode string
+
+const (
+	ValueAtCost   ValuationMode = "cost"
+	ValueAtThen   ValuationMode = "then"
+	ValueAtEnd    ValuationMode = "end"
+	ValueAtMarket ValuationMode = "market"
+)
+
+func ParseValuationMode(mode string) (ValuationMode, error) {
+	switch m := ValuationMode(mode); m {
+	case ValueAtCost, ValueAtThen, ValueAtEnd, ValueAtMarket:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown valuation '%s' (cost, then, end or market)", mode)
+}
+
+type Valuation struct {
+	Mode   ValuationMode
+	Target Commodity
+	End    time.Time // of the report, for 'end'
+	Prices PriceHistory
+}
+
+// ValuedLine is the balance of an account in the target commodity,
+// or in its own commodity when no price was found
+type ValuedLine struct {
+	Section string // assets, liabilities, revenue or expenses
+	Account string
+	Value
+	Note    string // the prices used
+	Missing bool
+}
+
+type ValuedReport struct {
+	Target   Commodity
+	Lines    []ValuedLine    // by section and account
+	Total    decimal.Decimal // net worth, or net income
+	Warnings []string        // the missing prices
+}
+
+// PriceHistory finds the price of a commodity at a date
+type PriceHistory struct {
+	pairs  map[string][]Price  // by 'code/currency', sorted by date
+	quotes map[string][]string // the currencies of the prices of a code
+}
+
+func NewPriceHistory(prices []Price) PriceHistory {
+	h := PriceHistory{
+		pairs:  make(map[string][]Price),
+		quotes: make(map[string][]string),
+	}
+	for _, price := range prices {
+		key := price.Code + "/" + price.Commodity.Code
+		if _, found := h.pairs[key]; !found {
+			h.quotes[price.Code] = append(h.quotes[price.Code], price.Commodity.Code)
+		}
+		h.pairs[key] = append(h.pairs[key], price)
+	}
+	for _, prices := range h.pairs {
+		slices.SortStableFunc(prices, func(a, b Price) int {
+			return a.Date.Compare(b.Date)
+		})
+	}
+	return h
+}
+
+// the latest price of the pair on or before the date, a zero
+// date means the latest price
+func (h PriceHistory) at(code, currency string, date time.Time) (Price, bool) {
+	prices := h.pairs[code+"/"+currency]
+	if date.IsZero() {
+		if len(prices) == 0 {
+			return Price{}, false
+		}
+		return prices[len(prices)-1], true
+	}
+	i := sort.Search(len(prices), func(i int) bool {
+		return prices[i].Date.After(date)
+	})
+	if i == 0 {
+		return Price{}, false
+	}
+	return prices[i-1], true
+}
+
+// the rate to convert from one commodity to the other, directly or
+// with the inverse price
+func (h PriceHistory) rate(from, to string, date time.Time) (decimal.Decimal, Price, bool) {
+	if price, found := h.at(from, to, date); found {
+		return price.Decimal, price, true
+	}
+	if price, found := h.at(to, from, date); found && !price.Decimal.IsZero() {
+		return decimal.NewFromInt(1).Div(price.Decimal), price, true
+	}
+	return decimal.Zero, Price{}, false
+}
+
+// Convert returns the amount in the target commodity, and the
+// prices used
+func (h PriceHistory) Convert(amount decimal.Decimal, from, to string, date time.Time) (decimal.Decimal, []Price, bool) {
+	if from == to {
+		return amount, nil, true
+	}
+	if rate, price, found := h.rate(from, to, date); found {
+		return amount.Mul(rate), []Price{price}, true
+	}
+	for _, quote := range h.quotes[from] {
+		first, p1, found := h.rate(from, quote, date)
+		if !found {
+			continue
+		}
+		second, p2, found := h.rate(quote, to, date)
+		if found {
+			return amount.Mul(first).Mul(second), []Price{p1, p2}, true
+		}
+	}
+	return decimal.Zero, nil, false
+}
+
+// BalanceStatement values the starting balance (OpeningBalance) and
+// the assets and liabilities of the transactions, the total is the
+// net worth
+func (v Valuation) BalanceStatement(start BalanceStatement, txs []Transaction) ValuedReport {
+	return v.report(append(start.transactions(), txs...), func(account string) string {
+		switch {
+		case strings.Contains(account, "asset"):
+			return "assets"
+		case strings.Contains(account, "liability") || strings.Contains(account, "liabilities"):
+			return "liabilities"
+		}
+		return ""
+	}, false)
+}
+
+// IncomeStatement values the revenue (positive) and expenses, the
+// total is the net income
+func (v Valuation) IncomeStatement(txs []Transaction) ValuedReport {
+	return v.report(txs, func(account string) string {
+		switch {
+		case strings.Contains(account, "income") || strings.Contains(account, "revenue"):
+			return "revenue"
+		case strings.Contains(account, "expense"):
+			return "expenses"
+		}
+		return ""
+	}, true)
+}
+
+type valuedKey struct {
+	section string
+	account string
+	code    string // the original commodity
+}
+
+type valuedSum struct {
+	amount    decimal.Decimal // in the original commodity
+	commodity Commodity
+	value     decimal.Decimal // in the target commodity
+	prices    map[string]Price
+	missing   string // the first missing conversion
+}
+
+func (v Valuation) report(txs []Transaction, section func(string) string, income bool) ValuedReport {
+	sums := make(map[valuedKey]*valuedSum)
+	var keys []valuedKey
+
+	for _, tx := range txs {
+		for _, post := range tx.Postings {
+			key := valuedKey{section(post.Account), post.Account, post.Commodity.Code}
+			if key.section == "" {
+				continue
+			}
+			sum, found := sums[key]
+			if !found {
+				sum = &valuedSum{commodity: post.Commodity, prices: make(map[string]Price)}
+				sums[key] = sum
+				keys = append(keys, key)
+			}
+			sum.amount = sum.amount.Add(post.Amount)
+
+			// valued posting by posting
+			switch v.Mode {
+			case ValueAtCost:
+				amount, code := post.Amount, post.Commodity.Code
+				if !post.UnitValue.Decimal.IsZero() {
+					amount, code = post.Amount.Mul(post.UnitValue.Decimal), post.UnitValue.Code
+				}
+				sum.add(v.Prices, amount, code, v.Target.Code, tx.Date)
+			case ValueAtThen:
+				sum.add(v.Prices, post.Amount, post.Commodity.Code, v.Target.Code, tx.Date)
+			}
+		}
+	}
+
+	report := ValuedReport{Target: v.Target}
+	for _, key := range keys {
+		sum := sums[key]
+		switch v.Mode {
+		case ValueAtEnd:
+			sum.add(v.Prices, sum.amount, key.code, v.Target.Code, v.End)
+		case ValueAtMarket:
+			sum.add(v.Prices, sum.amount, key.code, v.Target.Code, time.Time{})
+		}
+
+		line := ValuedLine{
+			Section: key.section,
+			Account: key.account,
+			Value:   Value{Decimal: sum.value, Commodity: v.Target},
+			Note:    sum.note(),
+		}
+		if sum.missing != "" {
+			line.Value = Value{Decimal: sum.amount, Commodity: sum.commodity}
+			line.Missing = true
+			report.Warnings = append(report.Warnings,
+				fmt.Sprintf("%s: no price for %s", key.account, sum.missing))
+		}
+		if income && key.section == "revenue" {
+			// by convention revenue is negative, shown as positive
+			line.Decimal = line.Decimal.Neg()
+		}
+		report.Lines = append(report.Lines, line)
+	}
+
+	// one line per account in the target commodity, the missing
+	// conversions stay separate
+	report.Lines = mergeValuedLines(report.Lines)
+	for _, line := range report.Lines {
+		if line.Missing {
+			continue
+		}
+		if income && line.Section == "expenses" {
+			report.Total = report.Total.Sub(line.Decimal)
+		} else {
+			report.Total = report.Total.Add(line.Decimal)
+		}
+	}
+	return report
+}
+
+func (sum *valuedSum) add(h PriceHistory, amount decimal.Decimal, from, to string, date time.Time) {
+	value, prices, found := h.Convert(amount, from, to, date)
+	if !found {
+		if sum.missing == "" {
+			sum.missing = fmt.Sprintf("%s in %s", from, to)
+			if !date.IsZero() {
+				sum.missing += " on " + date.Format("2006/01/02")
+			}
+		}
+		return
+	}
+	sum.value = sum.value.Add(value)
+	for _, price := range prices {
+		sum.prices[price.Code+"/"+price.Commodity.Code] = price
+	}
+}
+
+// the latest price used of each pair: 'VTI 245.1 USD (2024/01/15)'
+func (sum *valuedSum) note() string {
+	pairs := make([]string, 0, len(sum.prices))
+	for pair := range sum.prices {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	notes := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		price := sum.prices[pair]
+		notes = append(notes, fmt.Sprintf("%s %s %s (%s)", price.Code, price.Decimal, price.Commodity.Code, price.Date.Format("2006/01/02")))
+	}
+	return strings.Join(notes, ", ")
+}
+
+func mergeValuedLines(lines []ValuedLine) []ValuedLine {
+	merged := make([]ValuedLine, 0, len(lines))
+	index := make(map[string]int)
+	for _, line := range lines {
+		key := line.Section + " " + line.Account
+		i, found := index[key]
+		if line.Missing || !found {
+			if !line.Missing {
+				index[key] = len(merged)
+			}
+			merged = append(merged, line)
+			continue
+		}
+		merged[i].Decimal = merged[i].Decimal.Add(line.Decimal)
+		merged[i].Note = strings.Trim(merged[i].Note+", "+line.Note, ", ")
+	}
+
+	sections := map[string]int{"assets": 0, "liabilities": 1, "revenue": 2, "expenses": 3}
+	slices.SortStableFunc(merged, func(a, b ValuedLine) int {
+		if a.Section != b.Section {
+			return sections[a.Section] - sections[b.Section]
+		}
+		return strings.Compare(a.Account, b.Account)
+	})
+	return merged
+}
+
+func (v Value) Str() string {
+	return commodityStringPadded(0, v.Commodity, v.Decimal)
+}
@@ -0,0 +1,158 @@
+package pta
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const valuationJournal = `
+P 2024/01/01 USD 1.30 CAD
+P 2024/01/10 VTI $240.00
+P 2024/02/01 USD 1.35 CAD
+P 2024/02/01 VTI $250.00
+
+2024/01/02 Payroll
+	assets:bank  $2,000.00
+	income:salary
+
+2024/01/10 Buy VTI
+	assets:brokerage  4 VTI @ $240.00
+	equity:trading   -4 VTI
+	equity:trading    $960.00
+	assets:bank      -$960.00
+
+2024/01/20 Coffee
+	expenses:coffee  5.00 CAD
+	assets:cash
+
+2024/01/25 Gold
+	assets:vault  1 XAU
+	equity:opening
+`
+
+func valuationTest(t *testing.T, mode ValuationMode) (Valuation, []Transaction) {
+	journal, txs := parseTestJournal(t, valuationJournal)
+	return Valuation{
+		Mode:   mode,
+		Target: CommodityFromCode("CAD"),
+		End:    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		Prices: NewPriceHistory(journal.AllPrices()),
+	}, txs
+}
+
+func parseTestJournal(t *testing.T, in string) (*Journal, []Transaction) {
+	d := NewDecoder(strings.NewReader(in))
+	var txs []Transaction
+	for tx, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	return d.Journal(), txs
+}
+
+func TestPriceHistory(t *testing.T) {
+	h := NewPriceHistory([]Price{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "USD", Value{decimal.RequireFromString("1.25"), CommodityFromCode("CAD")}},
+		{time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), "VTI", Value{decimal.New(200, 0), CommodityFromCode("USD")}},
+	})
+	jan5 := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	jan15 := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		from, to string
+		date     time.Time
+		expected string
+		found    bool
+	}{
+		{"USD", "CAD", jan5, "12.5", true},
+		{"CAD", "USD", jan5, "8", true},     // inverse
+		{"VTI", "CAD", jan15, "2500", true}, // through USD
+		{"VTI", "CAD", jan5, "", false},     // before the first price
+		{"VTI", "CAD", time.Time{}, "2500", true},
+		{"XAU", "CAD", jan15, "", false},
+	}
+	for _, c := range cases {
+		value, _, found := h.Convert(decimal.New(10, 0), c.from, c.to, c.date)
+		if found != c.found || (found && value.String() != c.expected) {
+			t.Errorf("%s to %s: expected %s %v, got %s %v", c.from, c.to, c.expected, c.found, value, found)
+		}
+	}
+}
+
+func TestValuationBalanceStatement(t *testing.T) {
+	cases := []struct {
+		mode      ValuationMode
+		brokerage string
+		total     string
+	}{
+		// 960 USD at 1.30
+		{ValueAtCost, "1248", "2595"},
+		// bought at 240 USD and 1.30
+		{ValueAtThen, "1248", "2595"},
+		// the prices of january
+		{ValueAtEnd, "1248", "2595"},
+		// 4 VTI at 250 USD and 1.35
+		{ValueAtMarket, "1350", "2749"},
+	}
+	for _, c := range cases {
+		v, txs := valuationTest(t, c.mode)
+		report := v.BalanceStatement(BalanceStatement{}, txs)
+
+		var brokerage *ValuedLine
+		for i, line := range report.Lines {
+			if line.Account == "assets:brokerage" {
+				brokerage = &report.Lines[i]
+			}
+		}
+		if brokerage == nil || brokerage.Decimal.String() != c.brokerage || brokerage.Code != "CAD" {
+			t.Errorf("%s: expected the brokerage at %s CAD, got %+v", c.mode, c.brokerage, brokerage)
+			continue
+		}
+		if !strings.Contains(brokerage.Note, "USD") {
+			t.Errorf("%s: expected a note of the prices, got '%s'", c.mode, brokerage.Note)
+		}
+		if report.Total.String() != c.total {
+			t.Errorf("%s: expected a net worth of %s, got %s", c.mode, c.total, report.Total)
+		}
+
+		// the gold has no price, it stays apart
+		if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "no price for XAU in CAD") {
+			t.Errorf("%s: expected a missing price warning, got %v", c.mode, report.Warnings)
+		}
+		last := report.Lines[len(report.Lines)-1]
+		if last.Account != "assets:vault" || !last.Missing || last.Code != "XAU" {
+			t.Errorf("%s: expected the gold in XAU, got %+v", c.mode, last)
+		}
+	}
+}
+
+func TestValuationIncomeStatement(t *testing.T) {
+	v, txs := valuationTest(t, ValueAtThen)
+	report := v.IncomeStatement(txs)
+
+	if len(report.Lines) != 2 || report.Lines[0].Section != "revenue" || report.Lines[1].Section != "expenses" {
+		t.Fatalf("expected revenue and expenses, got %+v", report.Lines)
+	}
+	if !report.Lines[0].Decimal.Equal(decimal.New(2600, 0)) {
+		t.Errorf("expected the salary at 2600 CAD, got %s", report.Lines[0].Decimal)
+	}
+	if !report.Total.Equal(decimal.New(2595, 0)) || len(report.Warnings) != 0 {
+		t.Errorf("expected a net income of 2595 CAD, got %s %v", report.Total, report.Warnings)
+	}
+}
+
+func TestParseValuationMode(t *testing.T) {
+	for _, mode := range []string{"cost", "then", "end", "market"} {
+		if _, err := ParseValuationMode(mode); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := ParseValuationMode("now"); err == nil {
+		t.Error("expected an error for an unknown valuation")
+	}
+}